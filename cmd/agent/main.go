@@ -2,37 +2,159 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
 	"github.com/iudanet/yp-metrics-go/internal/agent"
+	"github.com/iudanet/yp-metrics-go/internal/agent/collector"
 	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/crypt"
+	"github.com/iudanet/yp-metrics-go/internal/logger"
+	"github.com/iudanet/yp-metrics-go/internal/pb"
+	"github.com/iudanet/yp-metrics-go/internal/sign"
 	"github.com/iudanet/yp-metrics-go/internal/storage"
+	"github.com/iudanet/yp-metrics-go/internal/tlsconfig"
+	"github.com/iudanet/yp-metrics-go/internal/trusted"
 )
 
 func main() {
-	ctxCancel, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	ctxStop, stop := signal.NotifyContext(ctxCancel, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	defer stop()
 
 	cfg, err := config.ParseAgentFlags()
 	if err != nil {
-		log.Printf("failed to parse agent flags: %v", err)
+		slog.Error("failed to parse agent flags", "error", err)
+		os.Exit(1)
+	}
+	l, err := logger.New(os.Stderr, cfg.LogLevel, cfg.LogJSON)
+	if err != nil {
+		slog.Error("failed to configure logging", "error", err)
 		os.Exit(1)
 	}
+	slog.SetDefault(l)
 	stor := storage.NewStorage()
 
-	a := agent.NewAgent(cfg, stor)
-	go a.PollWorker()
-	go a.ReportWorker()
+	collectors, err := collector.Default(collector.Options{
+		Mountpoints: cfg.DiskMounts,
+		Processes:   cfg.Processes,
+	}).Select(cfg.Collectors, cfg.DisabledCollectors)
+	if err != nil {
+		slog.Error("failed to select collectors", "error", err)
+		os.Exit(1)
+	}
+	labels, err := agentLabels(cfg)
+	if err != nil {
+		slog.Error("failed to build agent labels", "error", err)
+		os.Exit(1)
+	}
+	opts := []agent.Option{agent.WithCollectors(collectors...), agent.WithLabels(labels)}
+	if cfg.CryptoKey != "" {
+		key, err := crypt.LoadPublicKey(cfg.CryptoKey)
+		if err != nil {
+			slog.Error("failed to load public key", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, agent.WithPublicKey(key))
+	}
+
+	var tlsCfg *tls.Config
+	if cfg.TLSEnabled() {
+		tlsCfg, err = tlsconfig.Client(cfg.TLSCA, cfg.TLSCert, cfg.TLSKey, cfg.TLSInsecure)
+		if err != nil {
+			slog.Error("failed to configure TLS", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, agent.WithTLSConfig(tlsCfg))
+	}
+
+	realIP, err := trusted.OutboundIP(serverAddress(cfg))
+	if err != nil {
+		// без X-Real-IP сервер с доверенной подсетью отклонит обновления, но без неё всё работает
+		slog.Error("failed to detect agent address", "error", err)
+	}
+	opts = append(opts, agent.WithRealIP(realIP))
+
+	switch cfg.Transport {
+	case "stdout":
+		opts = append(opts, agent.WithTransport(agent.NewWriterTransport(os.Stdout)))
+	case "file":
+		f, err := os.OpenFile(cfg.OutputPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			slog.Error("failed to open output file", "error", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		opts = append(opts, agent.WithTransport(agent.NewWriterTransport(f)))
+	case "grpc":
+		creds := insecure.NewCredentials()
+		if tlsCfg != nil {
+			creds = credentials.NewTLS(tlsCfg)
+		}
+		conn, err := grpc.NewClient(cfg.GRPCAddress,
+			grpc.WithTransportCredentials(creds),
+			grpc.WithChainUnaryInterceptor(
+				trusted.UnaryClientInterceptor(realIP),
+				sign.UnaryClientInterceptor(cfg.Key),
+			),
+		)
+		if err != nil {
+			slog.Error("failed to create grpc client", "error", err)
+			os.Exit(1)
+		}
+		defer conn.Close()
+		opts = append(opts, agent.WithGRPC(pb.NewMetricsClient(conn)))
+	}
+
+	a := agent.NewAgent(cfg, stor, opts...)
+	if err := a.Run(ctx); err != nil {
+		slog.Error("agent stopped with error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("agent stopped")
+}
 
-	select {
-	case <-ctxStop.Done():
-		log.Println("Agent stopped")
-	case <-ctxCancel.Done():
-		log.Println("Agent canceled")
+// serverAddress возвращает host:port сервера, к которому подключается выбранный транспорт
+func serverAddress(cfg *config.AgentConfig) string {
+	if cfg.Transport == "grpc" {
+		return cfg.GRPCAddress
+	}
+	u, err := url.Parse(agent.BaseURL(cfg.MetricServerHost))
+	if err != nil || u.Port() != "" {
+		return cfg.MetricServerHost
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// agentLabels собирает метки агента: host, agent_id и статические метки из конфигурации.
+// Статические метки могут переопределить автоматические.
+func agentLabels(cfg *config.AgentConfig) (map[string]string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+	// по умолчанию agent_id совпадает с именем хоста, чтобы перезапуск не порождал новые ряды
+	agentID := cfg.AgentID
+	if agentID == "" {
+		agentID = host
+	}
+	labels := map[string]string{
+		"host":     host,
+		"agent_id": agentID,
 	}
+	maps.Copy(labels, cfg.Labels)
+	return labels, nil
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/iudanet/yp-metrics-go/internal/crypt"
+)
+
+// keygen создаёт пару ключей RSA: закрытый для сервера (-crypto-key) и открытый для агента
+func main() {
+	bits := flag.Int("bits", 4096, "RSA key size in bits")
+	privPath := flag.String("private", "private.pem", "private key output path")
+	pubPath := flag.String("public", "public.pem", "public key output path")
+	flag.Parse()
+
+	privPEM, pubPEM, err := crypt.GenerateKeyPair(*bits)
+	if err != nil {
+		log.Printf("failed to generate keys: %v", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*privPath, privPEM, 0o600); err != nil {
+		log.Printf("failed to write private key: %v", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*pubPath, pubPEM, 0o644); err != nil {
+		log.Printf("failed to write public key: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("keys written to %s and %s", *privPath, *pubPath)
+}
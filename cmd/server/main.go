@@ -1,27 +1,298 @@
 package main
 
 import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/iudanet/yp-metrics-go/internal/alert"
+	"github.com/iudanet/yp-metrics-go/internal/compress"
 	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/crypt"
+	"github.com/iudanet/yp-metrics-go/internal/history"
+	"github.com/iudanet/yp-metrics-go/internal/logger"
+	"github.com/iudanet/yp-metrics-go/internal/pb"
 	"github.com/iudanet/yp-metrics-go/internal/server"
+	"github.com/iudanet/yp-metrics-go/internal/sign"
+	"github.com/iudanet/yp-metrics-go/internal/statsd"
 	"github.com/iudanet/yp-metrics-go/internal/storage"
+	"github.com/iudanet/yp-metrics-go/internal/tlsconfig"
+	"github.com/iudanet/yp-metrics-go/internal/trusted"
+)
+
+// Таймауты HTTP-сервера: медленные клиенты не должны удерживать соединения бесконечно
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 10 * time.Second
+	writeTimeout      = 10 * time.Second
+	idleTimeout       = 60 * time.Second
 )
 
+// alertWebhookTimeout ограничивает один запрос к вебхуку алертов
+const alertWebhookTimeout = 10 * time.Second
+
 func main() {
-	storage := storage.NewStorage()
-	cfg := config.ParseServerFlags()
-	svc := server.NewService(storage, cfg)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	cfg, err := config.ParseServerFlags()
+	if err != nil {
+		slog.Error("failed to parse server flags", "error", err)
+		os.Exit(1)
+	}
+	l, err := logger.New(os.Stderr, cfg.LogLevel, cfg.LogJSON)
+	if err != nil {
+		slog.Error("failed to configure logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(l)
+
+	if err := run(ctx, cfg); err != nil {
+		slog.Error("server stopped with error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("server stopped")
+}
+
+// run обслуживает запросы до отмены ctx, затем дожидается завершения активных
+// запросов (не дольше ShutdownTimeout) и сбрасывает хранилище
+func run(ctx context.Context, cfg *config.ServerConfig) error {
+	repo, closer, err := newStorage(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to init storage: %w", err)
+	}
+
+	var svcOpts []server.Option
+	if cfg.HistorySize > 0 {
+		store := history.NewStore(cfg.HistorySize, time.Duration(cfg.HistoryRetention)*time.Second)
+		repo = history.Wrap(repo, store)
+		svcOpts = append(svcOpts, server.WithHistory(store))
+	}
+
+	if cfg.AlertRules != "" {
+		engine, err := newAlertEngine(cfg, repo)
+		if err != nil {
+			return err
+		}
+		go engine.Run(ctx, time.Duration(cfg.AlertInterval)*time.Second)
+		svcOpts = append(svcOpts, server.WithAlerts(engine))
+	}
+
+	var privateKey *rsa.PrivateKey
+	if cfg.CryptoKey != "" {
+		privateKey, err = crypt.LoadPrivateKey(cfg.CryptoKey)
+		if err != nil {
+			return fmt.Errorf("failed to load private key: %w", err)
+		}
+	}
+
+	var subnet *net.IPNet
+	if cfg.TrustedSubnet != "" {
+		_, subnet, err = net.ParseCIDR(cfg.TrustedSubnet)
+		if err != nil {
+			return fmt.Errorf("failed to parse trusted subnet: %w", err)
+		}
+	}
+
+	var tlsCfg *tls.Config
+	if cfg.TLSCert != "" {
+		tlsCfg, err = tlsconfig.Server(cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA)
+		if err != nil {
+			return err
+		}
+	}
+
+	var statsdConn net.PacketConn
+	if cfg.StatsdAddress != "" {
+		statsdConn, err = net.ListenPacket("udp", cfg.StatsdAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen statsd: %w", err)
+		}
+	}
+
+	var grpcListener net.Listener
+	if cfg.GRPCAddress != "" {
+		grpcListener, err = net.Listen("tcp", cfg.GRPCAddress)
+		if err != nil {
+			return fmt.Errorf("failed to listen grpc: %w", err)
+		}
+	}
+
+	svc := server.NewService(repo, cfg, svcOpts...)
 	// chi отключен для проходждения тестов. хотел сделать с нативным новым роутером.
 	_ = chi.NewRouter()
 	m := http.NewServeMux()
-	m.HandleFunc(`POST /update/{typeMetrics}/{name}/{value}`, svc.UpdateMetric)
+	// изменять метрики можно только из доверенной подсети, чтение остаётся открытым
+	onlyTrusted := trusted.Middleware(subnet)
+	m.Handle(`POST /update/{typeMetrics}/{name}/{value}`, onlyTrusted(http.HandlerFunc(svc.UpdateMetric)))
+	m.Handle(`POST /update/{$}`, onlyTrusted(http.HandlerFunc(svc.UpdateMetricJSON)))
+	m.Handle(`POST /updates/{$}`, onlyTrusted(http.HandlerFunc(svc.UpdateBatch)))
 	m.HandleFunc(`GET /value/{typeMetrics}/{name}`, svc.GetMetric)
+	m.HandleFunc(`POST /value/{$}`, svc.GetMetricJSON)
+	m.HandleFunc(`GET /history/{typeMetrics}/{name}`, svc.GetHistory)
+	m.HandleFunc(`GET /alerts`, svc.GetAlerts)
+	m.HandleFunc(`GET /metrics`, svc.GetPrometheus)
 	m.HandleFunc(`GET /{$}`, svc.GetIndex)
 
-	err := http.ListenAndServe(cfg.MetricServerHost, m)
+	srv := &http.Server{
+		Addr:              cfg.MetricServerHost,
+		Handler:           logger.Middleware(slog.Default())(decryptUpdates(privateKey, compress.GzipMiddleware(sign.Middleware(cfg.Key)(m)))),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		TLSConfig:         tlsCfg,
+	}
+
+	// буфер на HTTP и gRPC, чтобы второй упавший сервер не блокировался
+	serveErr := make(chan error, 2)
+	go func() {
+		if tlsCfg != nil {
+			// сертификат уже загружен в TLSConfig
+			serveErr <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	var grpcSrv *grpc.Server
+	if grpcListener != nil {
+		grpcOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+			server.LoggingInterceptor,
+			trusted.UnaryServerInterceptor(subnet, pb.Metrics_UpdateMetrics_FullMethodName),
+			sign.UnaryServerInterceptor(cfg.Key),
+		)}
+		if tlsCfg != nil {
+			grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+		}
+		grpcSrv = grpc.NewServer(grpcOpts...)
+		pb.RegisterMetricsServer(grpcSrv, server.NewGRPCService(svc))
+		go func() {
+			serveErr <- grpcSrv.Serve(grpcListener)
+		}()
+	}
+
+	// приёмник StatsD останавливается отдельно, до финального сброса хранилища
+	statsdCtx, stopStatsd := context.WithCancel(ctx)
+	defer stopStatsd()
+	statsdDone := make(chan error, 1)
+	if statsdConn != nil {
+		go func() {
+			statsdDone <- statsd.NewListener(repo, statsd.WithTrustedSubnet(subnet)).Serve(statsdCtx, statsdConn)
+		}()
+	} else {
+		close(statsdDone)
+	}
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		errs = append(errs, fmt.Errorf("server stopped: %w", err))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain connections: %w", err))
+	}
+	if grpcSrv != nil {
+		stopGRPC(shutdownCtx, grpcSrv)
+	}
+	stopStatsd()
+	if err := <-statsdDone; err != nil {
+		errs = append(errs, fmt.Errorf("statsd listener stopped: %w", err))
+	}
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush storage: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// decryptUpdates расшифровывает тела только на маршрутах обновления метрик.
+// Расшифровка идёт до распаковки gzip, поэтому маршруты выбираются отдельным роутером снаружи.
+func decryptUpdates(priv *rsa.PrivateKey, next http.Handler) http.Handler {
+	decrypted := crypt.Middleware(priv)(next)
+	m := http.NewServeMux()
+	m.Handle("/update/", decrypted)
+	m.Handle("/updates/", decrypted)
+	m.Handle("/", next)
+	return m
+}
+
+// stopGRPC дожидается завершения активных вызовов, а по истечении ctx обрывает их
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// newAlertEngine загружает правила алертов и подключает вебхук, если он задан
+func newAlertEngine(cfg *config.ServerConfig, reader storage.MetricReader) (*alert.Engine, error) {
+	if cfg.AlertInterval <= 0 {
+		return nil, errors.New("alert interval must be positive")
+	}
+	rules, err := alert.LoadRules(cfg.AlertRules)
+	if err != nil {
+		return nil, err
+	}
+	var opts []alert.Option
+	if cfg.AlertWebhook != "" {
+		opts = append(opts, alert.WithNotifier(alert.NewWebhook(cfg.AlertWebhook, &http.Client{Timeout: alertWebhookTimeout})))
+	}
+	return alert.NewEngine(reader, rules, opts...), nil
+}
+
+// newStorage выбирает хранилище по конфигурации. closer не nil,
+// если хранилищу нужна финальная запись перед остановкой.
+func newStorage(ctx context.Context, cfg *config.ServerConfig) (storage.Repository, io.Closer, error) {
+	if cfg.DatabaseDSN != "" {
+		db, err := storage.NewPostgresStorage(ctx, cfg.DatabaseDSN, storage.WithRetryDelays(cfg.RetryDelays))
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, db, nil
+	}
+	if cfg.SQLitePath != "" {
+		db, err := storage.NewSQLiteStorage(ctx, cfg.SQLitePath, storage.WithRetryDelays(cfg.RetryDelays))
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, db, nil
+	}
+	if cfg.FileStoragePath == "" {
+		return storage.NewStorage(), nil, nil
+	}
+	if cfg.StoreInterval < 0 {
+		return nil, nil, errors.New("store interval must not be negative")
+	}
+	fs, err := storage.NewFileStorage(cfg.FileStoragePath, time.Duration(cfg.StoreInterval)*time.Second, cfg.Restore)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
+	go fs.Run(ctx)
+	return fs, fs, nil
 }
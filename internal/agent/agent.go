@@ -1,151 +1,546 @@
 package agent
 
 import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
-	"runtime"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/iudanet/yp-metrics-go/internal/agent/collector"
+	"github.com/iudanet/yp-metrics-go/internal/compress"
 	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/crypt"
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/pb"
+	"github.com/iudanet/yp-metrics-go/internal/retry"
+	"github.com/iudanet/yp-metrics-go/internal/sign"
 	"github.com/iudanet/yp-metrics-go/internal/storage"
-	"github.com/iudanet/yp-metrics-go/internal/utils"
+	"github.com/iudanet/yp-metrics-go/internal/trusted"
 )
 
 type Agent struct {
-	memstats *runtime.MemStats
-	config   *config.AgentConfig
+	config *config.AgentConfig
 	// storage  storage.Repository
 	writer  storage.MetricWriter
 	counter storage.CounterIncrementer
 	reader  storage.MetricReader
+	// publicKey шифрует тела запросов, если задан
+	publicKey  *rsa.PublicKey
+	retry      retry.Policy
+	collectors []collector.Collector
+	// labels добавляются ко всем отправляемым метрикам
+	labels map[string]string
+	// grpc — клиент для транспорта gRPC
+	grpc pb.MetricsClient
+	// realIP передаётся серверу в X-Real-IP для проверки доверенной подсети
+	realIP string
+	// transport доставляет снимки метрик, по умолчанию выбирается по конфигурации
+	transport Transport
+	client    *http.Client
+	// tlsConfig применяется к транспорту client после всех опций
+	tlsConfig *tls.Config
+	// baseURL — адрес сервера со схемой http или https, без завершающего слеша
+	baseURL string
 }
 
-func NewAgent(cfg *config.AgentConfig, storage storage.Repository) *Agent {
+// Option задаёт необязательные параметры агента
+type Option func(*Agent)
+
+// WithCollectors заменяет встроенные коллекторы переданными
+func WithCollectors(collectors ...collector.Collector) Option {
+	return func(a *Agent) {
+		a.collectors = collectors
+	}
+}
+
+// WithLabels задаёт метки, которые агент прикрепляет к каждой метрике
+func WithLabels(labels map[string]string) Option {
+	return func(a *Agent) {
+		a.labels = labels
+	}
+}
+
+// WithHTTPClient задаёт HTTP-клиент для запросов к серверу, например с настроенным TLS
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *Agent) {
+		a.client = client
+	}
+}
+
+// WithTLSConfig включает TLS для HTTP-запросов к серверу.
+// Настройка применяется к клиенту по умолчанию или переданному через WithHTTPClient
+// независимо от порядка опций; адрес сервера без схемы считается https.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(a *Agent) {
+		a.tlsConfig = cfg
+	}
+}
+
+// WithRealIP задаёт адрес агента, который отправляется в заголовке X-Real-IP
+func WithRealIP(ip string) Option {
+	return func(a *Agent) {
+		a.realIP = ip
+	}
+}
+
+// WithPublicKey включает шифрование тел запросов открытым ключом сервера
+func WithPublicKey(key *rsa.PublicKey) Option {
+	return func(a *Agent) {
+		a.publicKey = key
+	}
+}
+
+// defaultRequestTimeout ограничивает один HTTP-запрос к серверу, если клиент не передан явно
+const defaultRequestTimeout = 10 * time.Second
+
+func NewAgent(cfg *config.AgentConfig, storage storage.Repository, opts ...Option) *Agent {
 	agent := &Agent{
-		memstats: &runtime.MemStats{},
-		config:   cfg,
-		writer:   storage,
-		counter:  storage,
-		reader:   storage,
+		config:  cfg,
+		writer:  storage,
+		counter: storage,
+		reader:  storage,
+		retry: retry.Policy{
+			Delays:    cfg.RetryDelays,
+			Retriable: isRetriable,
+		},
+		collectors: collector.Default(collector.Options{}).All(),
+		client:     &http.Client{Timeout: defaultRequestTimeout},
+		baseURL:    BaseURL(cfg.MetricServerHost),
+	}
+	for _, opt := range opts {
+		opt(agent)
+	}
+	if agent.tlsConfig != nil {
+		agent.client = withTLS(agent.client, agent.tlsConfig)
+		if !strings.Contains(cfg.MetricServerHost, "://") {
+			agent.baseURL = "https://" + cfg.MetricServerHost
+		}
+	}
+	if agent.transport == nil {
+		agent.transport = agent.defaultTransport()
 	}
 	return agent
 }
 
-func (a *Agent) GetMetrics() {
-	// увеличиваем каждую иттерацию
-	a.counter.IncrCounter("PollCount")
-	// получаем статистику памяти
-	a.getMemStats()
-	// получаем рандомное число
-	a.writer.SetGauge("RandomValue", utils.GetRandomNumber())
-}
-
-func (a *Agent) getMemStats() {
-	runtime.ReadMemStats(a.memstats)
-	a.memStatsMapper()
-}
-
-func (a *Agent) memStatsMapper() {
-	a.writer.SetGauge("Alloc", float64(a.memstats.Alloc))
-	a.writer.SetGauge("BuckHashSys", float64(a.memstats.BuckHashSys))
-	a.writer.SetGauge("Frees", float64(a.memstats.Frees))
-	a.writer.SetGauge("GCCPUFraction", a.memstats.GCCPUFraction)
-	a.writer.SetGauge("GCSys", float64(a.memstats.GCSys))
-	a.writer.SetGauge("HeapAlloc", float64(a.memstats.HeapAlloc))
-	a.writer.SetGauge("HeapIdle", float64(a.memstats.HeapIdle))
-	a.writer.SetGauge("HeapInuse", float64(a.memstats.HeapInuse))
-	a.writer.SetGauge("HeapObjects", float64(a.memstats.HeapObjects))
-	a.writer.SetGauge("HeapReleased", float64(a.memstats.HeapReleased))
-	a.writer.SetGauge("HeapSys", float64(a.memstats.HeapSys))
-	a.writer.SetGauge("LastGC", float64(a.memstats.LastGC))
-	a.writer.SetGauge("Lookups", float64(a.memstats.Lookups))
-	a.writer.SetGauge("MCacheInuse", float64(a.memstats.MCacheInuse))
-	a.writer.SetGauge("MCacheSys", float64(a.memstats.MCacheSys))
-	a.writer.SetGauge("Mallocs", float64(a.memstats.Mallocs))
-	a.writer.SetGauge("MSpanInuse", float64(a.memstats.MSpanInuse))
-	a.writer.SetGauge("MSpanSys", float64(a.memstats.MSpanSys))
-	a.writer.SetGauge("NextGC", float64(a.memstats.NextGC))
-	a.writer.SetGauge("NumForcedGC", float64(a.memstats.NumForcedGC))
-	a.writer.SetGauge("NumGC", float64(a.memstats.NumGC))
-	a.writer.SetGauge("OtherSys", float64(a.memstats.OtherSys))
-	a.writer.SetGauge("PauseTotalNs", float64(a.memstats.PauseTotalNs))
-	a.writer.SetGauge("StackInuse", float64(a.memstats.StackInuse))
-	a.writer.SetGauge("StackSys", float64(a.memstats.StackSys))
-	a.writer.SetGauge("Sys", float64(a.memstats.Sys))
-	a.writer.SetGauge("TotalAlloc", float64(a.memstats.TotalAlloc))
-}
-
-func (a *Agent) PollWorker() {
-	for {
-		a.GetMetrics()
-		time.Sleep(time.Duration(a.config.PollInterval))
+// GetMetrics опрашивает все коллекторы параллельно, каждый в своей горутине,
+// и записывает результаты в хранилище агента. Ошибка одного коллектора
+// не мешает остальным; возвращаются объединённые ошибки.
+func (a *Agent) GetMetrics(ctx context.Context) error {
+	errs := make([]error, len(a.collectors))
+	var wg sync.WaitGroup
+	for i, c := range a.collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.collect(ctx, c); err != nil {
+				errs[i] = fmt.Errorf("collector %s: %w", c.Name(), err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
 
+// collect опрашивает один коллектор и записывает его метрики
+func (a *Agent) collect(ctx context.Context, c collector.Collector) error {
+	metrics, err := c.Collect(ctx)
+	if err != nil {
+		return err
 	}
+	for _, m := range metrics {
+		switch m.Type {
+		case models.Gauge:
+			err = a.writer.SetGauge(m.Name, m.Value)
+		case models.Counter:
+			err = a.writer.SetCounter(m.Name, m.Delta)
+		default:
+			err = fmt.Errorf("metric %s has unknown type %q", m.Name, m.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// withTLS возвращает копию client, транспорт которой использует cfg; сам client не меняется.
+// Транспорт, отличный от *http.Transport, настроить нельзя — он остаётся как есть.
+func withTLS(client *http.Client, cfg *tls.Config) *http.Client {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		slog.Warn("TLS config is not applied to a custom HTTP transport", "transport", fmt.Sprintf("%T", t))
+		return client
+	}
+	transport.TLSClientConfig = cfg
+	copied := *client
+	copied.Transport = transport
+	return &copied
 }
-func (a *Agent) ReportWorker() {
+
+// flushTimeout ограничивает финальную отправку метрик при остановке агента
+const flushTimeout = 10 * time.Second
+
+// Run запускает сбор и отправку метрик и блокируется до отмены ctx.
+// После остановки циклов отправляет последний собранный снимок метрик.
+// Возвращает объединённые ошибки конфигурации и финальной отправки; nil — штатная остановка.
+func (a *Agent) Run(ctx context.Context) error {
+	var errs []error
+	if a.config.PollInterval <= 0 {
+		errs = append(errs, fmt.Errorf("poll interval must be positive, got %d", a.config.PollInterval))
+	}
+	if a.config.ReportInterval <= 0 {
+		errs = append(errs, fmt.Errorf("report interval must be positive, got %d", a.config.ReportInterval))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		a.PollWorker(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		a.ReportWorker(ctx)
+	}()
+	wg.Wait()
+
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+	defer cancel()
+	if err := a.flush(flushCtx); err != nil {
+		return fmt.Errorf("final flush failed: %w", err)
+	}
+	return nil
+}
+
+// flush синхронно отправляет текущий снимок метрик
+func (a *Agent) flush(ctx context.Context) error {
+	jobs, err := a.reportJobs()
+	if err != nil {
+		return err
+	}
+	errs := make([]error, 0, len(jobs))
+	for _, job := range jobs {
+		errs = append(errs, job(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// PollWorker собирает метрики раз в PollInterval до отмены ctx.
+// При неположительном интервале логирует ошибку и сразу возвращается.
+func (a *Agent) PollWorker(ctx context.Context) {
+	if a.config.PollInterval <= 0 {
+		slog.Error("poll interval must be positive", "interval", a.config.PollInterval)
+		return
+	}
+	ticker := time.NewTicker(time.Duration(a.config.PollInterval) * time.Second)
+	defer ticker.Stop()
 	for {
-		counter, err := a.reader.GetMapCounter()
-		if err != nil {
-			log.Println("Ошибка получения счетчика:", err)
-			continue
+		if err := a.GetMetrics(ctx); err != nil {
+			slog.Error("failed to collect metrics", "error", err)
 		}
-		for nameCouner, valueCounter := range counter {
-			err = a.PushCounter(nameCouner, valueCounter)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
 
+// ReportWorker раз в ReportInterval отправляет снимок метрик через пул из RateLimit воркеров.
+// Возвращается после отмены ctx, когда все воркеры завершились,
+// или сразу при неположительном интервале.
+func (a *Agent) ReportWorker(ctx context.Context) {
+	if a.config.ReportInterval <= 0 {
+		slog.Error("report interval must be positive", "interval", a.config.ReportInterval)
+		return
+	}
+	jobs := make(chan reportJob)
+	var wg sync.WaitGroup
+	for range max(a.config.RateLimit, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.sender(ctx, jobs)
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(time.Duration(a.config.ReportInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		a.enqueueReport(ctx, jobs)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
-		gaugeMap, err := a.reader.GetMapGauge()
-		if err != nil {
-			log.Println("Ошибка получения счетчика:", err)
-			continue
+	}
+}
+
+// reportJob — одна отправка на сервер, выполняемая воркером пула
+type reportJob func(ctx context.Context) error
+
+// sender выполняет отправки из очереди, пока она не закрыта
+func (a *Agent) sender(ctx context.Context, jobs <-chan reportJob) {
+	for job := range jobs {
+		if err := job(ctx); err != nil {
+			slog.Error("failed to report metrics", "error", err)
 		}
-		for nameGauge, valueGauge := range gaugeMap {
-			err = a.PushGauge(nameGauge, valueGauge)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
+	}
+}
 
+// enqueueReport ставит отправки текущего снимка в очередь; прерывается при отмене ctx
+func (a *Agent) enqueueReport(ctx context.Context, jobs chan<- reportJob) {
+	report, err := a.reportJobs()
+	if err != nil {
+		slog.Error("failed to prepare report", "error", err)
+		return
+	}
+	for _, job := range report {
+		select {
+		case <-ctx.Done():
+			return
+		case jobs <- job:
 		}
-		time.Sleep(time.Duration(a.config.ReportInterval) * time.Second)
 	}
+}
+
+// reportJobs разбивает снимок метрик на отправки: по заданию на метрику для
+// транспортов с отдельным запросом на метрику, иначе одно задание на весь снимок
+func (a *Agent) reportJobs() ([]reportJob, error) {
+	metrics, err := a.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	a.logReport(metrics)
+	if _, ok := a.transport.(perMetric); !ok {
+		return []reportJob{func(ctx context.Context) error {
+			return a.transport.Send(ctx, metrics)
+		}}, nil
+	}
+	jobs := make([]reportJob, 0, len(metrics))
+	for _, m := range metrics {
+		jobs = append(jobs, func(ctx context.Context) error {
+			return a.transport.Send(ctx, []models.Metrics{m})
+		})
+	}
+	return jobs, nil
+}
 
+// logReport пишет в лог, сколько метрик каждого типа уходит в отчёте
+func (a *Agent) logReport(metrics []models.Metrics) {
+	var gauges, counters int
+	for _, m := range metrics {
+		switch m.MType {
+		case models.Gauge:
+			gauges++
+		case models.Counter:
+			counters++
+		}
+	}
+	slog.Info("reporting metrics", "gauges", gauges, "counters", counters)
 }
-func (a *Agent) PushCounter(name string, value int64) error {
+
+// snapshot собирает текущие значения метрик из хранилища агента
+func (a *Agent) snapshot() ([]models.Metrics, error) {
+	counters, err := a.reader.GetMapCounter()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counters: %w", err)
+	}
+	gauges, err := a.reader.GetMapGauge()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gauges: %w", err)
+	}
+	metrics := make([]models.Metrics, 0, len(counters)+len(gauges))
+	for name, value := range counters {
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Counter, Delta: &value, Labels: a.labels})
+	}
+	for name, value := range gauges {
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Gauge, Value: &value, Labels: a.labels})
+	}
+	return metrics, nil
+}
+
+func (a *Agent) PushCounter(ctx context.Context, name string, value int64) error {
 	//	POST /update/counter/someMetric/527 HTTP/1.1
 	//
 	// Host: localhost:8080
 	// Content-Length: 0
 	// Content-Type: text/plain
-	req, err := http.Post(fmt.Sprintf("http://%s/update/%s/%s/%d", a.config.MetricServerHost, "counter", name, value), "text/plain", nil)
+	err := a.send(ctx, a.endpoint("update", models.Counter, name, strconv.FormatInt(value, 10))+a.labelQuery(), "text/plain", nil)
 	if err != nil {
-		return fmt.Errorf("unable to send request to server: %w", err)
-	}
-	defer req.Body.Close()
-	if req.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to push counter metric: %s", req.Status)
+		return fmt.Errorf("failed to push counter metric: %w", err)
 	}
 	return nil
 }
 
-func (a *Agent) PushGauge(name string, value float64) error {
+func (a *Agent) PushGauge(ctx context.Context, name string, value float64) error {
 	//	POST /update/gauge/someMetric/527 HTTP/1.1
 	//
 	// Host: localhost:8080
 	// Content-Length: 0
 	// Content-Type: text/plain
-	req, err := http.Post(fmt.Sprintf("http://%s/update/%s/%s/%f", a.config.MetricServerHost, "gauge", name, value), "text/plain", nil)
+	err := a.send(ctx, a.endpoint("update", models.Gauge, name, strconv.FormatFloat(value, 'f', -1, 64))+a.labelQuery(), "text/plain", nil)
+	if err != nil {
+		return fmt.Errorf("failed to push gauge metric: %w", err)
+	}
+	return nil
+}
+
+// labelQuery передаёт метки агента параметрами запроса для API с метрикой в пути
+func (a *Agent) labelQuery() string {
+	if len(a.labels) == 0 {
+		return ""
+	}
+	query := make(url.Values, len(a.labels))
+	for k, v := range a.labels {
+		query.Set(k, v)
+	}
+	return "?" + query.Encode()
+}
+
+func (a *Agent) PushBatch(ctx context.Context, metrics []models.Metrics) error {
+	//	POST /updates/ HTTP/1.1
+	//
+	// Host: localhost:8080
+	// Content-Type: application/json
+	body, err := json.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+	err = a.send(ctx, a.endpoint("updates", ""), "application/json", body)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics batch: %w", err)
+	}
+	return nil
+}
+
+func (a *Agent) PushJSON(ctx context.Context, metric models.Metrics) error {
+	//	POST /update/ HTTP/1.1
+	//
+	// Host: localhost:8080
+	// Content-Type: application/json
+	body, err := json.Marshal(metric)
 	if err != nil {
-		return fmt.Errorf("unable to send request to server: %w", err)
+		return fmt.Errorf("failed to marshal metric: %w", err)
 	}
-	defer req.Body.Close()
-	if req.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to push counter metric: %s", req.Status)
+	err = a.send(ctx, a.endpoint("update", ""), "application/json", body)
+	if err != nil {
+		return fmt.Errorf("failed to push %s metric: %w", metric.MType, err)
 	}
 	return nil
 }
+
+// StatusError — ответ сервера с неуспешным HTTP-статусом
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected response status: " + e.Status
+}
+
+// isRetriable отбирает ошибки, после которых запись на сервере не применена,
+// поэтому повтор не удвоит приращение счётчиков:
+//   - отказ в соединении — запрос не ушёл;
+//   - ответ 5xx — хранилища сервера откатывают неудачную запись, а обработчики
+//     не отвечают ошибкой после того, как запись применена;
+//   - коды gRPC из isRetriableGRPC.
+//
+// Таймауты и 504 не повторяются: сервер мог получить запрос и выполнить его.
+func isRetriable(err error) bool {
+	if isRetriableGRPC(err) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError && statusErr.StatusCode != http.StatusGatewayTimeout
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// send отправляет запрос и повторяет его при временных ошибках согласно RetryDelays
+func (a *Agent) send(ctx context.Context, url, contentType string, body []byte) error {
+	return a.retry.Do(ctx, func() error {
+		resp, err := a.post(ctx, url, contentType, body)
+		if err != nil {
+			return fmt.Errorf("unable to send request to server: %w", err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		}
+		return nil
+	})
+}
+
+// post отправляет тело запроса: подписывает его при заданном ключе,
+// сжимает gzip, если размер не меньше CompressMinSize, и шифрует при заданном открытом ключе
+func (a *Agent) post(ctx context.Context, url, contentType string, body []byte) (*http.Response, error) {
+	var hash string
+	if a.config.Key != "" {
+		hash = sign.Sum(body, a.config.Key)
+	}
+	compressed := len(body) > 0 && len(body) >= a.config.CompressMinSize
+	if compressed {
+		gz, err := compress.Gzip(body)
+		if err != nil {
+			return nil, err
+		}
+		body = gz
+	}
+	encrypted := len(body) > 0 && a.publicKey != nil
+	if encrypted {
+		enc, err := crypt.Encrypt(a.publicKey, body)
+		if err != nil {
+			return nil, err
+		}
+		body = enc
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if hash != "" {
+		req.Header.Set(sign.HeaderName, hash)
+	}
+	if encrypted {
+		req.Header.Set(crypt.HeaderName, crypt.Scheme)
+	}
+	if a.realIP != "" {
+		req.Header.Set(trusted.HeaderName, a.realIP)
+	}
+	return a.client.Do(req)
+}
@@ -1,13 +1,27 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/iudanet/yp-metrics-go/internal/agent/collector"
+	"github.com/iudanet/yp-metrics-go/internal/compress"
 	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/crypt"
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/sign"
 	"github.com/iudanet/yp-metrics-go/internal/storage"
+	"github.com/iudanet/yp-metrics-go/internal/trusted"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -30,11 +44,24 @@ func (c *TestAgentConfig) GetMetricServerHost() string {
 	return c.MetricServerHost
 }
 
+// withTestCollectors оставляет коллекторы, которые не читают /proc хоста
+func withTestCollectors() Option {
+	return WithCollectors(collector.NewPollCount(), collector.NewRuntime(), collector.NewRandom())
+}
+
 func TestAgent(t *testing.T) {
 	// Создаем тестовый HTTP сервер
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	var batch []models.Metrics
+	server := httptest.NewServer(compress.GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/updates/" {
+			batch = nil
+			if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
 		w.WriteHeader(http.StatusOK)
-	}))
+	})))
 	defer server.Close()
 
 	tests := []struct {
@@ -44,7 +71,7 @@ func TestAgent(t *testing.T) {
 		{
 			name: "test_metrics_collection",
 			fn: func(t *testing.T, a *Agent) {
-				a.GetMetrics()
+				require.NoError(t, a.GetMetrics(context.Background()))
 
 				gauges, err := a.reader.GetMapGauge()
 				require.NoError(t, err)
@@ -58,17 +85,33 @@ func TestAgent(t *testing.T) {
 		{
 			name: "test_push_counter",
 			fn: func(t *testing.T, a *Agent) {
-				err := a.PushCounter("test", 10)
+				err := a.PushCounter(context.Background(), "test", 10)
 				assert.NoError(t, err)
 			},
 		},
 		{
 			name: "test_push_gauge",
 			fn: func(t *testing.T, a *Agent) {
-				err := a.PushGauge("test", 10.5)
+				err := a.PushGauge(context.Background(), "test", 10.5)
 				assert.NoError(t, err)
 			},
 		},
+		{
+			name: "test_push_batch",
+			fn: func(t *testing.T, a *Agent) {
+				require.NoError(t, a.GetMetrics(context.Background()))
+
+				err := reportBatch(a)
+				require.NoError(t, err)
+
+				gauges, err := a.reader.GetMapGauge()
+				require.NoError(t, err)
+				counters, err := a.reader.GetMapCounter()
+				require.NoError(t, err)
+				assert.Len(t, batch, len(gauges)+len(counters))
+				assert.Contains(t, batch, models.Metrics{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(1))})
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -81,9 +124,425 @@ func TestAgent(t *testing.T) {
 			serverHost := server.URL[7:]
 			cfg.MetricServerHost = serverHost // Удаляем "http://" из адреса
 			store := storage.NewStorage()
-			agent := NewAgent(cfg, store)
+			agent := NewAgent(cfg, store, withTestCollectors())
 
 			tt.fn(t, agent)
 		})
 	}
 }
+
+func TestPostCompression(t *testing.T) {
+	var encoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		body     string
+		encoding string
+	}{
+		{
+			name: "small_body_not_compressed",
+			body: `[]`,
+		},
+		{
+			name:     "large_body_compressed",
+			body:     `[{"id":"Alloc","type":"gauge","value":1}]`,
+			encoding: "gzip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.AgentConfig{
+				MetricServerHost: server.URL[7:],
+				CompressMinSize:  16,
+			}
+			agent := NewAgent(cfg, storage.NewStorage())
+
+			resp, err := agent.post(context.Background(), server.URL, "application/json", []byte(tt.body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.encoding, encoding)
+		})
+	}
+}
+
+func TestPostSignature(t *testing.T) {
+	const key = "secret"
+	var signed bool
+	server := httptest.NewServer(compress.GzipMiddleware(sign.Middleware(key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signed = true
+		w.WriteHeader(http.StatusOK)
+	}))))
+	defer server.Close()
+
+	cfg := &config.AgentConfig{
+		MetricServerHost: server.URL[7:],
+		Key:              key,
+	}
+	agent := NewAgent(cfg, storage.NewStorage(), withTestCollectors())
+
+	require.NoError(t, agent.PushCounter(context.Background(), "test", 1))
+	assert.True(t, signed)
+
+	signed = false
+	require.NoError(t, agent.GetMetrics(context.Background()))
+	require.NoError(t, reportBatch(agent))
+	assert.True(t, signed)
+
+	signed = false
+	cfg.Key = "other"
+	assert.Error(t, agent.PushGauge(context.Background(), "test", 1))
+	assert.False(t, signed)
+}
+
+func TestPostRealIP(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	server := httptest.NewServer(trusted.Middleware(subnet)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer server.Close()
+
+	cfg := &config.AgentConfig{MetricServerHost: server.URL[7:]}
+	require.NoError(t, NewAgent(cfg, storage.NewStorage(), WithRealIP("10.1.2.3")).PushGauge(context.Background(), "test", 1))
+
+	var statusErr *StatusError
+	err = NewAgent(cfg, storage.NewStorage(), WithRealIP("192.168.1.1")).PushGauge(context.Background(), "test", 1)
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusForbidden, statusErr.StatusCode)
+}
+
+func TestPostEncryption(t *testing.T) {
+	privPEM, pubPEM, err := crypt.GenerateKeyPair(2048)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "private.pem"), privPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "public.pem"), pubPEM, 0o644))
+	priv, err := crypt.LoadPrivateKey(filepath.Join(dir, "private.pem"))
+	require.NoError(t, err)
+	pub, err := crypt.LoadPublicKey(filepath.Join(dir, "public.pem"))
+	require.NoError(t, err)
+
+	const key = "secret"
+	var batch []models.Metrics
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(crypt.Middleware(priv)(compress.GzipMiddleware(sign.Middleware(key)(handler))))
+	defer server.Close()
+
+	cfg := &config.AgentConfig{
+		MetricServerHost: server.URL[7:],
+		Key:              key,
+	}
+	agent := NewAgent(cfg, storage.NewStorage(), WithPublicKey(pub), withTestCollectors())
+
+	require.NoError(t, agent.GetMetrics(context.Background()))
+	require.NoError(t, reportBatch(agent))
+	assert.Contains(t, batch, models.Metrics{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(1))})
+
+	plain := NewAgent(cfg, storage.NewStorage(), withTestCollectors())
+	require.NoError(t, plain.GetMetrics(context.Background()))
+	assert.Error(t, reportBatch(plain), "server must reject unencrypted body")
+}
+
+func TestPushRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name:      "retry_on_5xx",
+			statuses:  []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK},
+			wantCalls: 3,
+		},
+		{
+			name:      "no_retry_on_4xx",
+			statuses:  []int{http.StatusBadRequest},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "attempts_exhausted",
+			statuses:  []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			wantErr:   true,
+			wantCalls: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[calls])
+				calls++
+			}))
+			defer server.Close()
+
+			cfg := &config.AgentConfig{
+				MetricServerHost: server.URL[7:],
+				RetryDelays:      []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond},
+			}
+			agent := NewAgent(cfg, storage.NewStorage())
+
+			err := agent.PushCounter(context.Background(), "test", 1)
+			if tt.wantErr {
+				var statusErr *StatusError
+				assert.ErrorAs(t, err, &statusErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestIsRetriable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	host := server.URL[7:]
+	server.Close()
+
+	cfg := &config.AgentConfig{MetricServerHost: host}
+	agent := NewAgent(cfg, storage.NewStorage())
+	err := agent.PushGauge(context.Background(), "test", 1)
+	require.Error(t, err)
+	assert.True(t, isRetriable(err), "connection refused must be retriable: %v", err)
+
+	assert.True(t, isRetriable(&StatusError{StatusCode: http.StatusInternalServerError}))
+	assert.False(t, isRetriable(&StatusError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, isRetriable(errors.New("other")))
+	assert.False(t, isRetriable(&StatusError{StatusCode: http.StatusGatewayTimeout}), "the server may have applied the request")
+	assert.False(t, isRetriable(&url.Error{Op: "Post", Err: &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}}), "timeouts must not be retried")
+}
+
+func TestReportWorker(t *testing.T) {
+	const rateLimit = 3
+	var (
+		mu       sync.Mutex
+		inFlight int
+		peak     int
+		received = make(map[string]bool)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		received[r.URL.Path] = true
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.AgentConfig{
+		ReportInterval:   10,
+		MetricServerHost: server.URL[7:],
+		RateLimit:        rateLimit,
+	}
+	agent := NewAgent(cfg, storage.NewStorage(), withTestCollectors())
+	require.NoError(t, agent.GetMetrics(context.Background()))
+	metrics, err := agent.snapshot()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.ReportWorker(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == len(metrics)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ReportWorker did not stop after cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, peak, rateLimit)
+	assert.Greater(t, peak, 1, "requests should be sent concurrently")
+}
+
+func TestWorkersNonPositiveInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *config.AgentConfig
+		worker func(a *Agent, ctx context.Context)
+	}{
+		{
+			name:   "poll_zero",
+			cfg:    &config.AgentConfig{PollInterval: 0},
+			worker: (*Agent).PollWorker,
+		},
+		{
+			name:   "report_negative",
+			cfg:    &config.AgentConfig{ReportInterval: -1},
+			worker: (*Agent).ReportWorker,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := NewAgent(tt.cfg, storage.NewStorage())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				tt.worker(agent, context.Background())
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("worker did not return on non-positive interval")
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		poll       int
+		report     int
+		wantErr    bool
+		wantPushes int
+	}{
+		{
+			name:       "clean_shutdown_with_final_flush",
+			status:     http.StatusOK,
+			poll:       1,
+			report:     1,
+			wantPushes: 2,
+		},
+		{
+			name:       "final_flush_failed",
+			status:     http.StatusBadRequest,
+			poll:       1,
+			report:     1,
+			wantErr:    true,
+			wantPushes: 2,
+		},
+		{
+			name:    "invalid_intervals",
+			status:  http.StatusOK,
+			poll:    0,
+			report:  -1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			pushes := 0
+			first := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				pushes++
+				if pushes == 1 {
+					close(first)
+				}
+				mu.Unlock()
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			cfg := &config.AgentConfig{
+				PollInterval:     tt.poll,
+				ReportInterval:   tt.report,
+				MetricServerHost: server.URL[7:],
+				Batch:            true,
+				RateLimit:        1,
+			}
+			agent := NewAgent(cfg, storage.NewStorage(), withTestCollectors())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				select {
+				case <-first:
+				case <-time.After(5 * time.Second):
+				}
+				cancel()
+			}()
+
+			err := agent.Run(ctx)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantPushes, pushes)
+		})
+	}
+}
+
+func TestLabels(t *testing.T) {
+	var (
+		query url.Values
+		batch []models.Metrics
+	)
+	server := httptest.NewServer(compress.GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		if r.URL.Path == "/updates/" {
+			if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer server.Close()
+
+	labels := map[string]string{"host": "web1", "agent_id": "a1"}
+	cfg := &config.AgentConfig{MetricServerHost: server.URL[7:]}
+	store := storage.NewStorage()
+	require.NoError(t, store.SetCounter("PollCount", 1))
+	agent := NewAgent(cfg, store, WithLabels(labels))
+
+	require.NoError(t, agent.PushGauge(context.Background(), "Alloc", 1))
+	assert.Equal(t, url.Values{"host": {"web1"}, "agent_id": {"a1"}}, query)
+
+	require.NoError(t, reportBatch(agent))
+	assert.Equal(t, []models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(1)), Labels: labels},
+	}, batch)
+}
+
+// reportBatch отправляет текущий снимок агента одним пакетом
+func reportBatch(a *Agent) error {
+	metrics, err := a.snapshot()
+	if err != nil {
+		return err
+	}
+	return a.PushBatch(context.Background(), metrics)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
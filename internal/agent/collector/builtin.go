@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/iudanet/yp-metrics-go/internal/utils"
+)
+
+// pollCount увеличивает счётчик PollCount при каждом сборе
+type pollCount struct{}
+
+func NewPollCount() Collector {
+	return pollCount{}
+}
+
+func (pollCount) Name() string {
+	return "pollcount"
+}
+
+func (pollCount) Collect(context.Context) ([]Metric, error) {
+	return []Metric{Counter("PollCount", 1)}, nil
+}
+
+// random возвращает RandomValue
+type random struct{}
+
+func NewRandom() Collector {
+	return random{}
+}
+
+func (random) Name() string {
+	return "random"
+}
+
+func (random) Collect(context.Context) ([]Metric, error) {
+	return []Metric{Gauge("RandomValue", utils.GetRandomNumber())}, nil
+}
+
+// runtimeStats читает статистику памяти процесса агента из runtime.MemStats
+type runtimeStats struct{}
+
+func NewRuntime() Collector {
+	return runtimeStats{}
+}
+
+func (runtimeStats) Name() string {
+	return "runtime"
+}
+
+func (runtimeStats) Collect(context.Context) ([]Metric, error) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return []Metric{
+		Gauge("Alloc", float64(m.Alloc)),
+		Gauge("BuckHashSys", float64(m.BuckHashSys)),
+		Gauge("Frees", float64(m.Frees)),
+		Gauge("GCCPUFraction", m.GCCPUFraction),
+		Gauge("GCSys", float64(m.GCSys)),
+		Gauge("HeapAlloc", float64(m.HeapAlloc)),
+		Gauge("HeapIdle", float64(m.HeapIdle)),
+		Gauge("HeapInuse", float64(m.HeapInuse)),
+		Gauge("HeapObjects", float64(m.HeapObjects)),
+		Gauge("HeapReleased", float64(m.HeapReleased)),
+		Gauge("HeapSys", float64(m.HeapSys)),
+		Gauge("LastGC", float64(m.LastGC)),
+		Gauge("Lookups", float64(m.Lookups)),
+		Gauge("MCacheInuse", float64(m.MCacheInuse)),
+		Gauge("MCacheSys", float64(m.MCacheSys)),
+		Gauge("Mallocs", float64(m.Mallocs)),
+		Gauge("MSpanInuse", float64(m.MSpanInuse)),
+		Gauge("MSpanSys", float64(m.MSpanSys)),
+		Gauge("NextGC", float64(m.NextGC)),
+		Gauge("NumForcedGC", float64(m.NumForcedGC)),
+		Gauge("NumGC", float64(m.NumGC)),
+		Gauge("OtherSys", float64(m.OtherSys)),
+		Gauge("PauseTotalNs", float64(m.PauseTotalNs)),
+		Gauge("StackInuse", float64(m.StackInuse)),
+		Gauge("StackSys", float64(m.StackSys)),
+		Gauge("Sys", float64(m.Sys)),
+		Gauge("TotalAlloc", float64(m.TotalAlloc)),
+	}, nil
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// Metric — одно значение, собранное коллектором
+type Metric struct {
+	Name  string
+	Type  string  // models.Gauge или models.Counter
+	Value float64 // значение gauge
+	Delta int64   // приращение counter
+}
+
+// Gauge создаёт метрику типа gauge
+func Gauge(name string, value float64) Metric {
+	return Metric{Name: name, Type: models.Gauge, Value: value}
+}
+
+// Counter создаёт приращение счётчика
+func Counter(name string, delta int64) Metric {
+	return Metric{Name: name, Type: models.Counter, Delta: delta}
+}
+
+// Collector — источник метрик агента
+type Collector interface {
+	// Name возвращает уникальное имя коллектора для включения и отключения в конфигурации
+	Name() string
+	// Collect возвращает текущие значения метрик
+	Collect(ctx context.Context) ([]Metric, error)
+}
+
+// Registry хранит коллекторы по именам в порядке регистрации
+type Registry struct {
+	collectors map[string]Collector
+	order      []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// Options настраивает встроенные коллекторы
+type Options struct {
+	// ProcRoot — точка монтирования procfs, по умолчанию DefaultProcRoot
+	ProcRoot string
+	// Mountpoints — точки монтирования для коллектора disk, пустой список означает все устройства
+	Mountpoints []string
+	// Processes — PID или имена процессов для коллектора process
+	Processes []string
+}
+
+// Default возвращает реестр со встроенными коллекторами.
+// Коллекторы, читающие procfs, регистрируются только на Linux.
+func Default(opts Options) *Registry {
+	procRoot := opts.ProcRoot
+	if procRoot == "" {
+		procRoot = DefaultProcRoot
+	}
+	r := NewRegistry()
+	builtin := append([]Collector{NewPollCount(), NewRuntime(), NewRandom()}, procCollectors(procRoot, opts)...)
+	for _, c := range builtin {
+		// имена встроенных коллекторов уникальны
+		_ = r.Register(c)
+	}
+	return r
+}
+
+// Register добавляет коллектор; повторная регистрация имени — ошибка
+func (r *Registry) Register(c Collector) error {
+	name := c.Name()
+	if _, ok := r.collectors[name]; ok {
+		return fmt.Errorf("collector %q already registered", name)
+	}
+	r.collectors[name] = c
+	r.order = append(r.order, name)
+	return nil
+}
+
+// Names возвращает имена зарегистрированных коллекторов
+func (r *Registry) Names() []string {
+	return slices.Clone(r.order)
+}
+
+// All возвращает все зарегистрированные коллекторы в порядке регистрации
+func (r *Registry) All() []Collector {
+	all := make([]Collector, 0, len(r.order))
+	for _, name := range r.order {
+		all = append(all, r.collectors[name])
+	}
+	return all
+}
+
+// Select возвращает коллекторы из enabled (все, если список пуст), кроме перечисленных в disabled.
+// Неизвестное имя в любом из списков — ошибка.
+func (r *Registry) Select(enabled, disabled []string) ([]Collector, error) {
+	for _, name := range slices.Concat(enabled, disabled) {
+		if _, ok := r.collectors[name]; !ok {
+			return nil, fmt.Errorf("unknown collector %q, available: %v", name, r.order)
+		}
+	}
+	names := r.order
+	if len(enabled) > 0 {
+		names = enabled
+	}
+	selected := make([]Collector, 0, len(names))
+	for _, name := range names {
+		if slices.Contains(disabled, name) {
+			continue
+		}
+		selected = append(selected, r.collectors[name])
+	}
+	return selected, nil
+}
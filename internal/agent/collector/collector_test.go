@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// stubCollector возвращает заранее заданные метрики
+type stubCollector struct {
+	name    string
+	metrics []Metric
+}
+
+func (s stubCollector) Name() string { return s.name }
+
+func (s stubCollector) Collect(context.Context) ([]Metric, error) { return s.metrics, nil }
+
+func names(collectors []Collector) []string {
+	result := make([]string, len(collectors))
+	for i, c := range collectors {
+		result[i] = c.Name()
+	}
+	return result
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register(stubCollector{name: "a"}))
+	require.NoError(t, r.Register(stubCollector{name: "b"}))
+	assert.Error(t, r.Register(stubCollector{name: "a"}))
+	assert.Equal(t, []string{"a", "b"}, r.Names())
+	assert.Equal(t, []string{"a", "b"}, names(r.All()))
+}
+
+func TestRegistrySelect(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, r.Register(stubCollector{name: name}))
+	}
+
+	tests := []struct {
+		name     string
+		enabled  []string
+		disabled []string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "all", expected: []string{"a", "b", "c"}},
+		{name: "enabled_order", enabled: []string{"c", "a"}, expected: []string{"c", "a"}},
+		{name: "disabled", disabled: []string{"b"}, expected: []string{"a", "c"}},
+		{name: "enabled_and_disabled", enabled: []string{"a", "b"}, disabled: []string{"a"}, expected: []string{"b"}},
+		{name: "unknown_enabled", enabled: []string{"x"}, wantErr: true},
+		{name: "unknown_disabled", disabled: []string{"x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := r.Select(tt.enabled, tt.disabled)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names(selected))
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	expected := []string{"pollcount", "runtime", "random"}
+	if runtime.GOOS == "linux" {
+		expected = append(expected, "system", "disk", "process", "netdev")
+	}
+	assert.Equal(t, expected, Default(Options{}).Names())
+}
+
+func TestBuiltinCollectors(t *testing.T) {
+	ctx := context.Background()
+
+	metrics, err := NewPollCount().Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Metric{Counter("PollCount", 1)}, metrics)
+
+	metrics, err = NewRandom().Collect(ctx)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "RandomValue", metrics[0].Name)
+	assert.Equal(t, models.Gauge, metrics[0].Type)
+
+	metrics, err = NewRuntime().Collect(ctx)
+	require.NoError(t, err)
+	gauges := gaugeMap(metrics)
+	for _, name := range []string{"Alloc", "HeapAlloc", "NumGC", "Sys", "TotalAlloc"} {
+		assert.Contains(t, gauges, name)
+	}
+	assert.Positive(t, gauges["Sys"])
+}
@@ -0,0 +1,11 @@
+package collector
+
+// procCollectors возвращает встроенные коллекторы, читающие procfs
+func procCollectors(procRoot string, opts Options) []Collector {
+	return []Collector{
+		NewSystem(procRoot),
+		NewDisk(procRoot, opts.Mountpoints),
+		NewProcess(procRoot, opts.Processes),
+		NewNetDev(procRoot),
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package collector
+
+// procCollectors возвращает пустой список: procfs есть только на Linux
+func procCollectors(string, Options) []Collector {
+	return nil
+}
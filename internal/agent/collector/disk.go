@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// diskUsage — объём файловой системы в байтах
+type diskUsage struct {
+	total uint64
+	free  uint64
+}
+
+// diskCollector сообщает занятость файловых систем по точкам монтирования.
+// Если точки не заданы, берутся все блочные устройства из <procRoot>/mounts.
+type diskCollector struct {
+	procRoot    string
+	mountpoints []string
+	statfs      func(path string) (diskUsage, error)
+}
+
+// NewDisk создаёт коллектор занятости дисков для mountpoints или для всех смонтированных устройств
+func NewDisk(procRoot string, mountpoints []string) Collector {
+	return &diskCollector{
+		procRoot:    procRoot,
+		mountpoints: mountpoints,
+		statfs:      statfs,
+	}
+}
+
+func (c *diskCollector) Name() string {
+	return "disk"
+}
+
+// Collect возвращает DiskTotal_<mount>, DiskFree_<mount> и DiskUsedPercent_<mount>
+func (c *diskCollector) Collect(context.Context) ([]Metric, error) {
+	mountpoints := c.mountpoints
+	discovered := len(mountpoints) == 0
+	if discovered {
+		var err error
+		mountpoints, err = c.readMounts()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	metrics := make([]Metric, 0, 3*len(mountpoints))
+	for _, mount := range mountpoints {
+		usage, err := c.statfs(mount)
+		if err != nil {
+			// найденные автоматически точки могут быть недоступны агенту
+			if discovered {
+				continue
+			}
+			return nil, fmt.Errorf("failed to stat %s: %w", mount, err)
+		}
+		var used float64
+		if usage.total > 0 {
+			used = 100 * float64(usage.total-usage.free) / float64(usage.total)
+		}
+		suffix := mountSuffix(mount)
+		metrics = append(metrics,
+			Gauge("DiskTotal_"+suffix, float64(usage.total)),
+			Gauge("DiskFree_"+suffix, float64(usage.free)),
+			Gauge("DiskUsedPercent_"+suffix, used),
+		)
+	}
+	return metrics, nil
+}
+
+// readMounts возвращает точки монтирования блочных устройств без повторов
+func (c *diskCollector) readMounts() ([]string, error) {
+	f, err := os.Open(filepath.Join(c.procRoot, "mounts"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	defer f.Close()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// proc, tmpfs, cgroup и прочие виртуальные файловые системы не связаны с устройством
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "/") {
+			continue
+		}
+		mount := unescapeMount(fields[1])
+		if !slices.Contains(mounts, mount) {
+			mounts = append(mounts, mount)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	return mounts, nil
+}
+
+// unescapeMount раскрывает восьмеричные последовательности вида \040, которыми ядро экранирует пробелы
+func unescapeMount(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// mountSuffix превращает путь точки монтирования в часть имени метрики: "/" → "root", "/var/lib" → "var_lib"
+func mountSuffix(mount string) string {
+	if mount == "/" {
+		return "root"
+	}
+	return sanitizeName(strings.Trim(mount, "/"))
+}
+
+// sanitizeName заменяет символы, недопустимые в имени метрики, на подчёркивание
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,16 @@
+package collector
+
+import "syscall"
+
+// statfs возвращает объём файловой системы, доступный непривилегированному пользователю
+func statfs(path string) (diskUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return diskUsage{}, err
+	}
+	bsize := uint64(st.Bsize)
+	return diskUsage{
+		total: st.Blocks * bsize,
+		free:  st.Bavail * bsize,
+	}, nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package collector
+
+import "errors"
+
+var errStatfsUnsupported = errors.New("disk usage is not supported on this platform")
+
+func statfs(string) (diskUsage, error) {
+	return diskUsage{}, errStatfsUnsupported
+}
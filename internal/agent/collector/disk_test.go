@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskCollector(t *testing.T) {
+	usage := map[string]diskUsage{
+		"/":             {total: 1000, free: 250},
+		"/var/lib/data": {total: 2000, free: 2000},
+		"/mnt/my disk":  {total: 0, free: 0},
+	}
+	statfs := func(path string) (diskUsage, error) {
+		u, ok := usage[path]
+		if !ok {
+			return diskUsage{}, errors.New("no such mount")
+		}
+		return u, nil
+	}
+
+	t.Run("discovered", func(t *testing.T) {
+		c := &diskCollector{procRoot: filepath.Join("testdata", "proc"), statfs: statfs}
+		assert.Equal(t, "disk", c.Name())
+
+		metrics, err := c.Collect(context.Background())
+		require.NoError(t, err)
+		gauges := gaugeMap(metrics)
+		assert.Len(t, gauges, 9)
+		assert.Equal(t, float64(1000), gauges["DiskTotal_root"])
+		assert.Equal(t, float64(250), gauges["DiskFree_root"])
+		assert.InDelta(t, 75.0, gauges["DiskUsedPercent_root"], 0.001)
+		assert.InDelta(t, 0.0, gauges["DiskUsedPercent_var_lib_data"], 0.001)
+		assert.Contains(t, gauges, "DiskTotal_mnt_my_disk")
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		c := &diskCollector{mountpoints: []string{"/var/lib/data"}, statfs: statfs}
+		metrics, err := c.Collect(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, float64(2000), gaugeMap(metrics)["DiskTotal_var_lib_data"])
+		assert.Len(t, metrics, 3)
+	})
+
+	t.Run("configured_missing", func(t *testing.T) {
+		c := &diskCollector{mountpoints: []string{"/missing"}, statfs: statfs}
+		_, err := c.Collect(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestUnescapeMount(t *testing.T) {
+	tests := map[string]string{
+		`/mnt/my\040disk`: "/mnt/my disk",
+		`/plain`:          "/plain",
+		`/tab\011x`:       "/tab\tx",
+		`/broken\04`:      `/broken\04`,
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, unescapeMount(in), in)
+	}
+}
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ifaceCounters — счётчики интерфейса из /proc/net/dev
+type ifaceCounters struct {
+	rxBytes, rxPackets uint64
+	txBytes, txPackets uint64
+}
+
+// netDevCollector сообщает трафик сетевых интерфейсов как приращения счётчиков.
+// Первый сбор только запоминает значения и отдаёт нулевые приращения,
+// чтобы перезапуск агента не добавлял на сервер трафик с момента загрузки системы.
+type netDevCollector struct {
+	procRoot string
+	mutex    sync.Mutex
+	prev     map[string]ifaceCounters
+}
+
+// NewNetDev создаёт коллектор трафика интерфейсов, читающий <procRoot>/net/dev
+func NewNetDev(procRoot string) Collector {
+	return &netDevCollector{procRoot: procRoot}
+}
+
+func (c *netDevCollector) Name() string {
+	return "netdev"
+}
+
+// Collect возвращает NetRxBytes_<iface>, NetRxPackets_<iface>, NetTxBytes_<iface> и NetTxPackets_<iface>
+func (c *netDevCollector) Collect(context.Context) ([]Metric, error) {
+	ifaces, names, err := c.readNetDev()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	metrics := make([]Metric, 0, 4*len(names))
+	for _, name := range names {
+		cur := ifaces[name]
+		prev, ok := c.prev[name]
+		if !ok {
+			prev = cur
+		}
+		suffix := sanitizeName(name)
+		metrics = append(metrics,
+			Counter("NetRxBytes_"+suffix, counterDelta(prev.rxBytes, cur.rxBytes)),
+			Counter("NetRxPackets_"+suffix, counterDelta(prev.rxPackets, cur.rxPackets)),
+			Counter("NetTxBytes_"+suffix, counterDelta(prev.txBytes, cur.txBytes)),
+			Counter("NetTxPackets_"+suffix, counterDelta(prev.txPackets, cur.txPackets)),
+		)
+	}
+	c.prev = ifaces
+	return metrics, nil
+}
+
+// counterDelta возвращает приращение; уменьшение значения означает сброс счётчика
+func counterDelta(prev, cur uint64) int64 {
+	if cur < prev {
+		return int64(cur)
+	}
+	return int64(cur - prev)
+}
+
+// readNetDev возвращает счётчики интерфейсов и их имена в порядке файла
+func (c *netDevCollector) readNetDev() (map[string]ifaceCounters, []string, error) {
+	f, err := os.Open(filepath.Join(c.procRoot, "net", "dev"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read net/dev: %w", err)
+	}
+	defer f.Close()
+
+	ifaces := make(map[string]ifaceCounters)
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// две строки заголовка не содержат двоеточия после имени интерфейса
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.Contains(name, "|") {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 10 {
+			return nil, nil, fmt.Errorf("invalid net/dev line for %s", strings.TrimSpace(name))
+		}
+		var values [4]uint64
+		// байты и пакеты приёма — поля 0 и 1, передачи — 8 и 9
+		for i, idx := range []int{0, 1, 8, 9} {
+			values[i], err = strconv.ParseUint(fields[idx], 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid net/dev counter: %w", err)
+			}
+		}
+		name = strings.TrimSpace(name)
+		ifaces[name] = ifaceCounters{
+			rxBytes:   values[0],
+			rxPackets: values[1],
+			txBytes:   values[2],
+			txPackets: values[3],
+		}
+		names = append(names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read net/dev: %w", err)
+	}
+	return ifaces, names, nil
+}
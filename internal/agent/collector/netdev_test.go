@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counterMap переводит результат сбора в карту приращений счётчиков по именам
+func counterMap(metrics []Metric) map[string]int64 {
+	counters := make(map[string]int64)
+	for _, m := range metrics {
+		counters[m.Name] = m.Delta
+	}
+	return counters
+}
+
+func TestNetDevCollector(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "net"), 0o755))
+	fixture, err := os.ReadFile(filepath.Join("testdata", "proc", "net", "dev"))
+	require.NoError(t, err)
+	devPath := filepath.Join(root, "net", "dev")
+	require.NoError(t, os.WriteFile(devPath, fixture, 0o644))
+
+	c := NewNetDev(root)
+	assert.Equal(t, "netdev", c.Name())
+
+	// первый сбор только запоминает значения
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, metrics, 8)
+	for _, delta := range counterMap(metrics) {
+		assert.Zero(t, delta)
+	}
+
+	next := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1500      15    0    0    0     0          0         0     1500      15    0    0    0     0       0          0
+  eth0:     100       1    0    0    0     0          0         0   200300    1503    0    0    0     0       0          0
+`
+	require.NoError(t, os.WriteFile(devPath, []byte(next), 0o644))
+
+	metrics, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		"NetRxBytes_lo":     500,
+		"NetRxPackets_lo":   5,
+		"NetTxBytes_lo":     500,
+		"NetTxPackets_lo":   5,
+		"NetRxBytes_eth0":   100, // счётчик сброшен
+		"NetRxPackets_eth0": 1,
+		"NetTxBytes_eth0":   300,
+		"NetTxPackets_eth0": 3,
+	}, counterMap(metrics))
+}
+
+func TestNetDevCollectorMissing(t *testing.T) {
+	_, err := NewNetDev(t.TempDir()).Collect(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// processCollector сообщает число открытых файловых дескрипторов и потоков
+// для целевых процессов, заданных PID или именем (содержимым /proc/<pid>/comm).
+// Число горутин доступно только для собственного процесса агента.
+type processCollector struct {
+	procRoot string
+	targets  []string
+}
+
+// NewProcess создаёт коллектор метрик процессов targets
+func NewProcess(procRoot string, targets []string) Collector {
+	return &processCollector{procRoot: procRoot, targets: targets}
+}
+
+func (c *processCollector) Name() string {
+	return "process"
+}
+
+// Collect возвращает Goroutines, а для каждого найденного процесса —
+// ProcessOpenFDs_<name>_<pid> и ProcessThreads_<name>_<pid>
+func (c *processCollector) Collect(context.Context) ([]Metric, error) {
+	metrics := []Metric{Gauge("Goroutines", float64(runtime.NumGoroutine()))}
+	if len(c.targets) == 0 {
+		return metrics, nil
+	}
+
+	pids, err := c.resolve()
+	if err != nil {
+		return nil, err
+	}
+	for _, pid := range pids {
+		m, err := c.collectProcess(pid)
+		// процесс мог завершиться между поиском и чтением
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+// resolve переводит цели в список PID: числа берутся как есть, имена ищутся среди всех процессов
+func (c *processCollector) resolve() ([]int, error) {
+	var pids []int
+	names := make(map[string]bool)
+	for _, target := range c.targets {
+		if pid, err := strconv.Atoi(target); err == nil {
+			pids = append(pids, pid)
+			continue
+		}
+		names[target] = true
+	}
+	if len(names) == 0 {
+		return pids, nil
+	}
+
+	entries, err := os.ReadDir(c.procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		comm, err := c.readComm(pid)
+		if err != nil {
+			continue
+		}
+		if names[comm] {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+func (c *processCollector) collectProcess(pid int) ([]Metric, error) {
+	comm, err := c.readComm(pid)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(c.procRoot, strconv.Itoa(pid))
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fds of %d: %w", pid, err)
+	}
+	threads, err := readThreads(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil, err
+	}
+	suffix := fmt.Sprintf("%s_%d", sanitizeName(comm), pid)
+	return []Metric{
+		Gauge("ProcessOpenFDs_"+suffix, float64(len(fds))),
+		Gauge("ProcessThreads_"+suffix, float64(threads)),
+	}, nil
+}
+
+func (c *processCollector) readComm(pid int) (string, error) {
+	data, err := os.ReadFile(filepath.Join(c.procRoot, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readThreads читает поле Threads из /proc/<pid>/status
+func readThreads(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || key != "Threads" {
+			continue
+		}
+		threads, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return 0, fmt.Errorf("invalid Threads in %s: %w", path, err)
+		}
+		return threads, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return 0, fmt.Errorf("%s has no Threads", path)
+}
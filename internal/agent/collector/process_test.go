@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessCollector(t *testing.T) {
+	procRoot := filepath.Join("testdata", "proc")
+
+	tests := []struct {
+		name     string
+		targets  []string
+		expected map[string]float64
+	}{
+		{
+			name:     "no_targets",
+			expected: map[string]float64{},
+		},
+		{
+			name:    "by_name",
+			targets: []string{"nginx"},
+			expected: map[string]float64{
+				"ProcessOpenFDs_nginx_42": 3,
+				"ProcessThreads_nginx_42": 4,
+			},
+		},
+		{
+			name:    "by_pid_and_missing",
+			targets: []string{"43", "999", "redis"},
+			expected: map[string]float64{
+				"ProcessOpenFDs_postgres_43": 1,
+				"ProcessThreads_postgres_43": 12,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewProcess(procRoot, tt.targets)
+			assert.Equal(t, "process", c.Name())
+
+			metrics, err := c.Collect(context.Background())
+			require.NoError(t, err)
+			gauges := gaugeMap(metrics)
+			assert.Positive(t, gauges["Goroutines"])
+			delete(gauges, "Goroutines")
+			assert.Equal(t, tt.expected, gauges)
+		})
+	}
+}
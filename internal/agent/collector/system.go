@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultProcRoot — точка монтирования procfs
+const DefaultProcRoot = "/proc"
+
+// cpuTimes — счётчики времени ядра из /proc/stat в тиках
+type cpuTimes struct {
+	idle  uint64
+	total uint64
+}
+
+// systemCollector собирает метрики хоста из procfs: память и загрузку каждого ядра.
+// Загрузка считается по разнице счётчиков между двумя сборами,
+// при первом сборе — с момента загрузки системы.
+type systemCollector struct {
+	procRoot string
+	mutex    sync.Mutex
+	prevCPU  []cpuTimes
+}
+
+// NewSystem создаёт коллектор метрик хоста, читающий procfs из procRoot
+func NewSystem(procRoot string) Collector {
+	return &systemCollector{procRoot: procRoot}
+}
+
+func (c *systemCollector) Name() string {
+	return "system"
+}
+
+// Collect возвращает TotalMemory, FreeMemory и CPUutilization1..N
+func (c *systemCollector) Collect(context.Context) ([]Metric, error) {
+	total, free, err := c.readMemInfo()
+	if err != nil {
+		return nil, err
+	}
+	cpus, err := c.readCPUTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	metrics := make([]Metric, 0, len(cpus)+2)
+	metrics = append(metrics, Gauge("TotalMemory", total), Gauge("FreeMemory", free))
+	for i, cur := range cpus {
+		var prev cpuTimes
+		if len(c.prevCPU) == len(cpus) {
+			prev = c.prevCPU[i]
+		}
+		metrics = append(metrics, Gauge(fmt.Sprintf("CPUutilization%d", i+1), utilization(prev, cur)))
+	}
+	c.prevCPU = cpus
+	return metrics, nil
+}
+
+// utilization возвращает долю занятого времени ядра в процентах между двумя замерами
+func utilization(prev, cur cpuTimes) float64 {
+	total := cur.total - prev.total
+	if total == 0 || cur.total < prev.total {
+		return 0
+	}
+	idle := cur.idle - prev.idle
+	return 100 * float64(total-idle) / float64(total)
+}
+
+// readMemInfo возвращает общий и свободный объём памяти в байтах
+func (c *systemCollector) readMemInfo() (total, free float64, err error) {
+	f, err := os.Open(filepath.Join(c.procRoot, "meminfo"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read meminfo: %w", err)
+	}
+	defer f.Close()
+
+	var foundTotal, foundFree bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || (key != "MemTotal" && key != "MemFree") {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return 0, 0, fmt.Errorf("empty meminfo value for %s", key)
+		}
+		kb, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid meminfo value for %s: %w", key, err)
+		}
+		if key == "MemTotal" {
+			total, foundTotal = float64(kb*1024), true
+		} else {
+			free, foundFree = float64(kb*1024), true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read meminfo: %w", err)
+	}
+	if !foundTotal || !foundFree {
+		return 0, 0, fmt.Errorf("meminfo has no MemTotal or MemFree")
+	}
+	return total, free, nil
+}
+
+// readCPUTimes читает счётчики отдельных ядер (строки cpu0..cpuN) из /proc/stat
+func (c *systemCollector) readCPUTimes() ([]cpuTimes, error) {
+	f, err := os.Open(filepath.Join(c.procRoot, "stat"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stat: %w", err)
+	}
+	defer f.Close()
+
+	var cpus []cpuTimes
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// строка "cpu" без номера — сумма по всем ядрам
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		var times cpuTimes
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s counter: %w", fields[0], err)
+			}
+			// guest и guest_nice уже учтены в user и nice
+			if i < 8 {
+				times.total += v
+			}
+			// idle и iowait
+			if i == 3 || i == 4 {
+				times.idle += v
+			}
+		}
+		cpus = append(cpus, times)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stat: %w", err)
+	}
+	if len(cpus) == 0 {
+		return nil, fmt.Errorf("stat has no per-cpu counters")
+	}
+	return cpus, nil
+}
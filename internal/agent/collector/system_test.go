@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gaugeMap переводит результат сбора в карту gauge по именам
+func gaugeMap(metrics []Metric) map[string]float64 {
+	gauges := make(map[string]float64)
+	for _, m := range metrics {
+		gauges[m.Name] = m.Value
+	}
+	return gauges
+}
+
+func TestSystemCollector(t *testing.T) {
+	c := NewSystem(filepath.Join("testdata", "proc"))
+	assert.Equal(t, "system", c.Name())
+
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	gauges := gaugeMap(metrics)
+	assert.Equal(t, float64(16384000*1024), gauges["TotalMemory"])
+	assert.Equal(t, float64(4096000*1024), gauges["FreeMemory"])
+	// первый сбор считается с момента загрузки: cpu0 занят 400 из 1000 тиков, cpu1 — 200 из 1000
+	assert.InDelta(t, 40.0, gauges["CPUutilization1"], 0.001)
+	assert.InDelta(t, 20.0, gauges["CPUutilization2"], 0.001)
+	assert.NotContains(t, gauges, "CPUutilization3")
+}
+
+func TestSystemCollectorDelta(t *testing.T) {
+	root := t.TempDir()
+	meminfo, err := os.ReadFile(filepath.Join("testdata", "proc", "meminfo"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "meminfo"), meminfo, 0o644))
+
+	writeStat := func(stat string) {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "stat"), []byte(stat), 0o644))
+	}
+
+	c := NewSystem(root)
+
+	writeStat("cpu  0 0 0 0 0 0 0 0\ncpu0 100 0 0 100 0 0 0 0\n")
+	_, err = c.Collect(context.Background())
+	require.NoError(t, err)
+
+	// за интервал ядро было занято 150 тиков из 200
+	writeStat("cpu  0 0 0 0 0 0 0 0\ncpu0 200 0 50 150 0 0 0 0\n")
+	metrics, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 75.0, gaugeMap(metrics)["CPUutilization1"])
+}
+
+func TestSystemCollectorErrors(t *testing.T) {
+	_, err := NewSystem(t.TempDir()).Collect(context.Background())
+	assert.Error(t, err, "missing procfs files must fail")
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "meminfo"), []byte("MemTotal: 10 kB\n"), 0o644))
+	_, err = NewSystem(root).Collect(context.Background())
+	assert.Error(t, err, "meminfo without MemFree must fail")
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/pb"
+)
+
+// WithGRPC переключает отправку метрик на gRPC: каждый отчёт уходит одним вызовом UpdateMetrics.
+// Подпись запросов настраивается интерцептором соединения, RSA-шифрование тел к gRPC не применяется.
+func WithGRPC(client pb.MetricsClient) Option {
+	return func(a *Agent) {
+		a.grpc = client
+		a.transport = grpcTransport{a}
+	}
+}
+
+// PushGRPC отправляет метрики вызовом UpdateMetrics с повторами при временных ошибках
+func (a *Agent) PushGRPC(ctx context.Context, metrics []models.Metrics) error {
+	req := &pb.UpdateMetricsRequest{Metrics: make([]*pb.Metric, len(metrics))}
+	for i, m := range metrics {
+		metric := &pb.Metric{Id: m.ID, Type: m.MType, Labels: m.Labels}
+		if m.Delta != nil {
+			metric.Delta = *m.Delta
+		}
+		if m.Value != nil {
+			metric.Value = *m.Value
+		}
+		req.Metrics[i] = metric
+	}
+	err := a.retry.Do(ctx, func() error {
+		// адрес сервера заполняется, только если вызов получил соединение
+		var p peer.Peer
+		_, err := a.grpc.UpdateMetrics(ctx, req, grpc.Peer(&p))
+		if err != nil && p.Addr == nil {
+			return fmt.Errorf("%w: %w", errNotSent, err)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push metrics over grpc: %w", err)
+	}
+	return nil
+}
+
+// errNotSent помечает вызов, который не дошёл до сервера: соединение не было установлено
+var errNotSent = errors.New("request was not sent")
+
+// isRetriableGRPC отбирает коды gRPC, при которых повтор имеет смысл.
+// ResourceExhausted означает, что сервер отклонил батч. Unavailable повторяется, только если
+// вызов не дошёл до сервера: при обрыве соединения после отправки батч мог быть применён.
+// DeadlineExceeded и Aborted не повторяются по той же причине — повтор удвоил бы счётчики.
+func isRetriableGRPC(err error) bool {
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch s.Code() {
+	case codes.ResourceExhausted:
+		return true
+	case codes.Unavailable:
+		return errors.Is(err, errNotSent)
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/pb"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+// fakeMetricsClient отвечает на UpdateMetrics ошибками из errs по очереди, затем успехом.
+// sent означает, что вызовы доходят до сервера: заполняется адрес в grpc.Peer.
+type fakeMetricsClient struct {
+	pb.MetricsClient
+	errs     []error
+	sent     bool
+	requests []*pb.UpdateMetricsRequest
+}
+
+func (c *fakeMetricsClient) UpdateMetrics(_ context.Context, req *pb.UpdateMetricsRequest, opts ...grpc.CallOption) (*pb.UpdateMetricsResponse, error) {
+	c.requests = append(c.requests, req)
+	for _, opt := range opts {
+		if p, ok := opt.(grpc.PeerCallOption); ok && c.sent {
+			p.PeerAddr.Addr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3200}
+		}
+	}
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return &pb.UpdateMetricsResponse{Metrics: req.GetMetrics()}, nil
+}
+
+func TestPushGRPC(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		sent      bool
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name:      "success",
+			wantCalls: 1,
+		},
+		{
+			name:      "retry_on_unavailable_before_connect",
+			errs:      []error{status.Error(codes.Unavailable, "down")},
+			wantCalls: 2,
+		},
+		{
+			// соединение оборвалось после отправки: батч мог быть применён
+			name:      "no_retry_on_unavailable_after_send",
+			errs:      []error{status.Error(codes.Unavailable, "connection reset")},
+			sent:      true,
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "retry_on_resource_exhausted",
+			errs:      []error{status.Error(codes.ResourceExhausted, "busy")},
+			sent:      true,
+			wantCalls: 2,
+		},
+		{
+			// сервер мог применить батч до истечения дедлайна
+			name:      "no_retry_on_deadline_exceeded",
+			errs:      []error{status.Error(codes.DeadlineExceeded, "slow")},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "no_retry_on_aborted",
+			errs:      []error{status.Error(codes.Aborted, "conflict")},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "no_retry_on_invalid_argument",
+			errs:      []error{status.Error(codes.InvalidArgument, "bad")},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeMetricsClient{errs: tt.errs, sent: tt.sent}
+			cfg := &config.AgentConfig{RetryDelays: []time.Duration{time.Millisecond, time.Millisecond}}
+			a := NewAgent(cfg, storage.NewStorage(), WithGRPC(client))
+
+			err := a.PushGRPC(context.Background(), []models.Metrics{
+				{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(3))},
+				{ID: "Alloc", MType: models.Gauge, Value: ptr(1.5), Labels: map[string]string{"host": "a"}},
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Len(t, client.requests, tt.wantCalls)
+
+			metrics := client.requests[0].GetMetrics()
+			require.Len(t, metrics, 2)
+			assert.Equal(t, int64(3), metrics[0].GetDelta())
+			assert.Equal(t, 1.5, metrics[1].GetValue())
+			assert.Equal(t, map[string]string{"host": "a"}, metrics[1].GetLabels())
+		})
+	}
+}
+
+func TestPushGRPCServerDown(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	calls := 0
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			calls++
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	cfg := &config.AgentConfig{RetryDelays: []time.Duration{time.Millisecond, time.Millisecond}}
+	a := NewAgent(cfg, storage.NewStorage(), WithGRPC(pb.NewMetricsClient(conn)))
+	err = a.PushGRPC(context.Background(), []models.Metrics{{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(1))}})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 3, calls, "a call that never reached the server is retried")
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// Transport доставляет снимок метрик получателю
+type Transport interface {
+	Send(ctx context.Context, metrics []models.Metrics) error
+}
+
+// perMetric отмечает транспорты, отправляющие каждую метрику отдельным запросом.
+// Снимок для них делится на задания по метрике, чтобы запросы шли параллельно через пул воркеров.
+type perMetric interface {
+	Transport
+	perMetric()
+}
+
+// WithTransport заменяет транспорт, выбранный по конфигурации
+func WithTransport(t Transport) Option {
+	return func(a *Agent) {
+		a.transport = t
+	}
+}
+
+// defaultTransport выбирает HTTP-транспорт по конфигурации
+func (a *Agent) defaultTransport() Transport {
+	switch {
+	case a.config.Transport == "json":
+		return jsonTransport{a}
+	case a.config.Batch:
+		return batchTransport{a}
+	default:
+		return pathTransport{a}
+	}
+}
+
+// BaseURL возвращает адрес сервера со схемой: адрес без схемы считается http
+func BaseURL(host string) string {
+	if strings.Contains(host, "://") {
+		return strings.TrimSuffix(host, "/")
+	}
+	return "http://" + host
+}
+
+// endpoint собирает URL запроса из базового адреса и экранированных сегментов пути
+func (a *Agent) endpoint(segments ...string) string {
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return a.baseURL + "/" + strings.Join(segments, "/")
+}
+
+// pathTransport отправляет каждую метрику запросом POST /update/{type}/{name}/{value}
+type pathTransport struct{ a *Agent }
+
+func (pathTransport) perMetric() {}
+
+func (t pathTransport) Send(ctx context.Context, metrics []models.Metrics) error {
+	for _, m := range metrics {
+		var err error
+		switch m.MType {
+		case models.Counter:
+			err = t.a.PushCounter(ctx, m.ID, *m.Delta)
+		case models.Gauge:
+			err = t.a.PushGauge(ctx, m.ID, *m.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonTransport отправляет каждую метрику JSON-телом на POST /update/
+type jsonTransport struct{ a *Agent }
+
+func (jsonTransport) perMetric() {}
+
+func (t jsonTransport) Send(ctx context.Context, metrics []models.Metrics) error {
+	for _, m := range metrics {
+		if err := t.a.PushJSON(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchTransport отправляет весь снимок одним запросом на POST /updates/
+type batchTransport struct{ a *Agent }
+
+func (t batchTransport) Send(ctx context.Context, metrics []models.Metrics) error {
+	return t.a.PushBatch(ctx, metrics)
+}
+
+// grpcTransport отправляет снимок одним вызовом UpdateMetrics
+type grpcTransport struct{ a *Agent }
+
+func (t grpcTransport) Send(ctx context.Context, metrics []models.Metrics) error {
+	return t.a.PushGRPC(ctx, metrics)
+}
+
+// WriterTransport пишет метрики в w по одной JSON-строке на метрику.
+// Нужен для отладки: вывод в stdout или файл вместо отправки на сервер.
+type WriterTransport struct {
+	mutex sync.Mutex
+	enc   *json.Encoder
+}
+
+func NewWriterTransport(w io.Writer) *WriterTransport {
+	return &WriterTransport{enc: json.NewEncoder(w)}
+}
+
+func (t *WriterTransport) Send(_ context.Context, metrics []models.Metrics) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, m := range metrics {
+		if err := t.enc.Encode(m); err != nil {
+			return fmt.Errorf("failed to write metric: %w", err)
+		}
+	}
+	return nil
+}
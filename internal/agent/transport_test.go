@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+func TestHTTPTransports(t *testing.T) {
+	metrics := []models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(3))},
+		{ID: "Alloc", MType: models.Gauge, Value: ptr(1.25)},
+	}
+
+	tests := []struct {
+		name      string
+		cfg       config.AgentConfig
+		wantJobs  int
+		wantPaths []string
+		wantBody  string
+	}{
+		{
+			name:      "path",
+			cfg:       config.AgentConfig{Transport: "http"},
+			wantJobs:  2,
+			wantPaths: []string{"/update/counter/PollCount/3", "/update/gauge/Alloc/1.25"},
+		},
+		{
+			name:      "json",
+			cfg:       config.AgentConfig{Transport: "json"},
+			wantJobs:  2,
+			wantPaths: []string{"/update/", "/update/"},
+			wantBody:  `{"id":"Alloc","type":"gauge","value":1.25}`,
+		},
+		{
+			name:      "batch",
+			cfg:       config.AgentConfig{Transport: "http", Batch: true},
+			wantJobs:  1,
+			wantPaths: []string{"/updates/"},
+			wantBody:  `[{"id":"PollCount","type":"counter","delta":3},{"id":"Alloc","type":"gauge","value":1.25}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				paths []string
+				body  string
+			)
+			// TLS-сервер проверяет, что агент работает с базовым адресом https://
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				paths = append(paths, r.URL.Path)
+				body = string(data)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			cfg := tt.cfg
+			cfg.MetricServerHost = server.URL
+			cfg.CompressMinSize = 1 << 20
+			a := NewAgent(&cfg, storage.NewStorage(), WithHTTPClient(server.Client()))
+
+			require.NoError(t, a.transport.Send(context.Background(), metrics))
+			assert.Equal(t, tt.wantPaths, paths)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, body)
+			}
+			_, split := a.transport.(perMetric)
+			assert.Equal(t, tt.wantJobs > 1, split)
+		})
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:8080", BaseURL("localhost:8080"))
+	assert.Equal(t, "https://metrics.example.com", BaseURL("https://metrics.example.com/"))
+
+	a := NewAgent(&config.AgentConfig{MetricServerHost: "localhost:8080"}, storage.NewStorage())
+	assert.Equal(t, "http://localhost:8080/update/gauge/a%2Fb/1", a.endpoint("update", "gauge", "a/b", "1"))
+	assert.Equal(t, "http://localhost:8080/updates/", a.endpoint("updates", ""))
+}
+
+func TestWriterTransport(t *testing.T) {
+	var buf bytes.Buffer
+	store := storage.NewStorage()
+	require.NoError(t, store.SetCounter("PollCount", 2))
+	require.NoError(t, store.SetGauge("Alloc", 1.5))
+	a := NewAgent(&config.AgentConfig{}, store, WithTransport(NewWriterTransport(&buf)))
+
+	require.NoError(t, a.flush(context.Background()))
+
+	var got []models.Metrics
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var m models.Metrics
+		require.NoError(t, dec.Decode(&m))
+		got = append(got, m)
+	}
+	assert.ElementsMatch(t, []models.Metrics{
+		{ID: "PollCount", MType: models.Counter, Delta: ptr(int64(2))},
+		{ID: "Alloc", MType: models.Gauge, Value: ptr(1.5)},
+	}, got)
+}
+
+// recordingTransport запоминает отправленные снимки
+type recordingTransport struct {
+	mutex sync.Mutex
+	sent  [][]models.Metrics
+}
+
+func (r *recordingTransport) Send(_ context.Context, metrics []models.Metrics) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sent = append(r.sent, metrics)
+	return nil
+}
+
+func TestWithTransport(t *testing.T) {
+	store := storage.NewStorage()
+	require.NoError(t, store.SetCounter("PollCount", 1))
+	require.NoError(t, store.SetGauge("Alloc", 1))
+	transport := &recordingTransport{}
+	a := NewAgent(&config.AgentConfig{}, store, WithTransport(transport))
+
+	jobs, err := a.reportJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1, "custom transports receive the whole snapshot")
+	require.NoError(t, jobs[0](context.Background()))
+	require.Len(t, transport.sent, 1)
+	assert.Len(t, transport.sent[0], 2)
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	cfg := &config.AgentConfig{MetricServerHost: server.URL}
+
+	// без CA сервера проверка сертификата не проходит
+	err := NewAgent(cfg, storage.NewStorage()).PushGauge(context.Background(), "Alloc", 1)
+	assert.Error(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	tlsCfg := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	a := NewAgent(cfg, storage.NewStorage(), WithTLSConfig(tlsCfg))
+	assert.NoError(t, a.PushGauge(context.Background(), "Alloc", 1))
+	assert.Equal(t, defaultRequestTimeout, a.client.Timeout)
+
+	t.Run("without_scheme", func(t *testing.T) {
+		cfg := &config.AgentConfig{MetricServerHost: server.Listener.Addr().String()}
+		a := NewAgent(cfg, storage.NewStorage(), WithTLSConfig(tlsCfg))
+		assert.Equal(t, "https://"+cfg.MetricServerHost, a.baseURL)
+		assert.NoError(t, a.PushGauge(context.Background(), "Alloc", 1))
+	})
+
+	t.Run("injected_client", func(t *testing.T) {
+		client := &http.Client{Timeout: time.Second}
+		// порядок опций не важен: TLS применяется к переданному клиенту
+		a := NewAgent(cfg, storage.NewStorage(), WithTLSConfig(tlsCfg), WithHTTPClient(client))
+		assert.NoError(t, a.PushGauge(context.Background(), "Alloc", 1))
+		assert.Equal(t, time.Second, a.client.Timeout)
+		assert.Nil(t, client.Transport, "the injected client must not be modified")
+	})
+}
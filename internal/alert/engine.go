@@ -0,0 +1,235 @@
+package alert
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+// Состояния алерта
+const (
+	StatePending  = "pending"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// notifyTimeout ограничивает одну отправку уведомлений
+const notifyTimeout = 10 * time.Second
+
+// Alert — состояние правила, условие которого выполняется или только что перестало выполняться
+type Alert struct {
+	Rule       string     `json:"rule"`
+	Metric     string     `json:"metric"`
+	State      string     `json:"state"`
+	Value      float64    `json:"value"`
+	ActiveAt   time.Time  `json:"active_at"`
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Notifier доставляет алерты, перешедшие в firing или resolved
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// Option задаёт необязательные параметры движка
+type Option func(*Engine)
+
+// WithNotifier добавляет получателя уведомлений
+func WithNotifier(n Notifier) Option {
+	return func(e *Engine) {
+		e.notifiers = append(e.notifiers, n)
+	}
+}
+
+// sample — значение ряда при предыдущем вычислении, нужно для rate
+type sample struct {
+	value float64
+	at    time.Time
+}
+
+// Engine периодически проверяет правила по текущим значениям хранилища.
+// Метки правила — фильтр: правило проверяется для каждого ряда с его именем,
+// у которого есть все метки правила, и алерт заводится на каждый такой ряд отдельно.
+// Условие, выполненное впервые, переводит алерт в pending; если оно держится
+// не меньше For — в firing. Когда условие firing-алерта перестаёт выполняться
+// или ряд пропадает, отправляется resolved и алерт удаляется.
+type Engine struct {
+	reader    storage.MetricReader
+	rules     []Rule
+	notifiers []Notifier
+
+	mutex  sync.Mutex
+	alerts map[alertID]*Alert
+	prev   map[alertID]sample
+}
+
+func NewEngine(reader storage.MetricReader, rules []Rule, opts ...Option) *Engine {
+	e := &Engine{
+		reader: reader,
+		rules:  rules,
+		alerts: make(map[alertID]*Alert),
+		prev:   make(map[alertID]sample),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// alertID различает алерты одного правила по рядам
+type alertID struct {
+	rule string
+	key  string
+}
+
+// Run вычисляет правила каждые interval до отмены ctx
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.Evaluate(ctx, now)
+		}
+	}
+}
+
+// Evaluate проверяет все правила на момент now и рассылает изменившиеся алерты
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) {
+	gauges, counters, err := e.snapshot()
+	if err != nil {
+		slog.Warn("alert: failed to read metrics", "error", err)
+		return
+	}
+
+	var changed []Alert
+	e.mutex.Lock()
+	for _, r := range e.rules {
+		series := r.series(gauges, counters)
+		for _, key := range slices.Sorted(maps.Keys(series)) {
+			value, ok := e.value(r, key, series[key], now)
+			if alert := e.transition(r, key, value, ok && operators[r.Op](value, float64(r.Threshold)), now); alert != nil {
+				changed = append(changed, *alert)
+			}
+		}
+		// ряды, которые пропали из хранилища, больше не удовлетворяют условию
+		for _, alert := range e.sortedAlerts() {
+			if _, ok := series[alert.Metric]; alert.Rule != r.Name || ok {
+				continue
+			}
+			if alert := e.transition(r, alert.Metric, 0, false, now); alert != nil {
+				changed = append(changed, *alert)
+			}
+		}
+		for id := range e.prev {
+			if _, ok := series[id.key]; id.rule == r.Name && !ok {
+				delete(e.prev, id)
+			}
+		}
+	}
+	e.mutex.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	for _, n := range e.notifiers {
+		if err := n.Notify(ctx, changed); err != nil {
+			slog.Error("failed to send alerts", "error", err)
+		}
+	}
+}
+
+// snapshot читает текущие значения всех рядов
+func (e *Engine) snapshot() (map[string]float64, map[string]int64, error) {
+	gauges, err := e.reader.GetMapGauge()
+	if err != nil {
+		return nil, nil, err
+	}
+	counters, err := e.reader.GetMapCounter()
+	if err != nil {
+		return nil, nil, err
+	}
+	return gauges, counters, nil
+}
+
+// value возвращает значение ряда key, с которым сравнивается порог правила.
+// ok равно false, если для rate ещё нет предыдущего замера.
+func (e *Engine) value(r Rule, key string, value float64, now time.Time) (float64, bool) {
+	if r.Func != FuncRate {
+		return value, true
+	}
+
+	id := alertID{rule: r.Name, key: key}
+	prev, ok := e.prev[id]
+	e.prev[id] = sample{value: value, at: now}
+	elapsed := now.Sub(prev.at).Seconds()
+	if !ok || elapsed <= 0 {
+		return 0, false
+	}
+	return (value - prev.value) / elapsed, true
+}
+
+// transition меняет состояние алерта правила r по ряду key и возвращает алерт, если о нём нужно уведомить
+func (e *Engine) transition(r Rule, key string, value float64, active bool, now time.Time) *Alert {
+	id := alertID{rule: r.Name, key: key}
+	alert, exists := e.alerts[id]
+	if !active {
+		if !exists {
+			return nil
+		}
+		delete(e.alerts, id)
+		// pending-алерт снимается молча: о нём не уведомляли
+		if alert.State != StateFiring {
+			return nil
+		}
+		alert.State = StateResolved
+		alert.ResolvedAt = &now
+		return alert
+	}
+
+	if !exists {
+		alert = &Alert{Rule: r.Name, Metric: key, State: StatePending, ActiveAt: now}
+		e.alerts[id] = alert
+	}
+	alert.Value = value
+	if alert.State == StatePending && now.Sub(alert.ActiveAt) >= time.Duration(r.For) {
+		alert.State = StateFiring
+		alert.FiredAt = &now
+		copied := *alert
+		return &copied
+	}
+	return nil
+}
+
+// sortedAlerts возвращает копии текущих алертов, отсортированные по правилу и ряду
+func (e *Engine) sortedAlerts() []Alert {
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		alerts = append(alerts, *a)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].Metric < alerts[j].Metric
+	})
+	return alerts
+}
+
+// Active возвращает pending и firing алерты, отсортированные по имени правила и ряду
+func (e *Engine) Active() []Alert {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.sortedAlerts()
+}
@@ -0,0 +1,175 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+var base = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// recorder запоминает уведомления, полученные вебхуком
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) handler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		var payload webhookPayload
+		if !assert.NoError(t, json.NewDecoder(req.Body).Decode(&payload)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		r.alerts = append(r.alerts, payload.Alerts...)
+		r.mu.Unlock()
+	})
+}
+
+func (r *recorder) states() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make([]string, len(r.alerts))
+	for i, a := range r.alerts {
+		states[i] = a.Rule + ":" + a.State
+	}
+	return states
+}
+
+func TestEngineThreshold(t *testing.T) {
+	rec := &recorder{}
+	hook := httptest.NewServer(rec.handler(t))
+	defer hook.Close()
+
+	repo := storage.NewStorage()
+	rules := []Rule{{Name: "HighHeap", Type: "gauge", Metric: "HeapAlloc", Op: ">", Threshold: 100, For: Duration(2 * time.Minute)}}
+	e := NewEngine(repo, rules, WithNotifier(NewWebhook(hook.URL, hook.Client())))
+	ctx := context.Background()
+
+	// метрики ещё нет — правило неактивно
+	e.Evaluate(ctx, base)
+	assert.Empty(t, e.Active())
+
+	require.NoError(t, repo.SetGauge("HeapAlloc", 150))
+	e.Evaluate(ctx, base.Add(time.Minute))
+	active := e.Active()
+	require.Len(t, active, 1)
+	assert.Equal(t, StatePending, active[0].State)
+	assert.Equal(t, 150.0, active[0].Value)
+	assert.Empty(t, rec.states(), "pending alerts are not sent")
+
+	e.Evaluate(ctx, base.Add(2*time.Minute))
+	assert.Equal(t, StatePending, e.Active()[0].State)
+
+	require.NoError(t, repo.SetGauge("HeapAlloc", 200))
+	e.Evaluate(ctx, base.Add(3*time.Minute))
+	active = e.Active()
+	require.Len(t, active, 1)
+	assert.Equal(t, StateFiring, active[0].State)
+	assert.Equal(t, 200.0, active[0].Value)
+	assert.Equal(t, base.Add(time.Minute), active[0].ActiveAt)
+	assert.Equal(t, []string{"HighHeap:firing"}, rec.states())
+
+	// повторная проверка не шлёт дубликат
+	e.Evaluate(ctx, base.Add(4*time.Minute))
+	assert.Len(t, rec.states(), 1)
+
+	require.NoError(t, repo.SetGauge("HeapAlloc", 50))
+	e.Evaluate(ctx, base.Add(5*time.Minute))
+	assert.Empty(t, e.Active())
+	assert.Equal(t, []string{"HighHeap:firing", "HighHeap:resolved"}, rec.states())
+}
+
+func TestEnginePendingResolvedSilently(t *testing.T) {
+	rec := &recorder{}
+	hook := httptest.NewServer(rec.handler(t))
+	defer hook.Close()
+
+	repo := storage.NewStorage()
+	rules := []Rule{{Name: "Low", Type: "gauge", Metric: "Free", Op: "<", Threshold: 10, For: Duration(time.Minute)}}
+	e := NewEngine(repo, rules, WithNotifier(NewWebhook(hook.URL, nil)))
+
+	require.NoError(t, repo.SetGauge("Free", 5))
+	e.Evaluate(context.Background(), base)
+	require.NoError(t, repo.SetGauge("Free", 50))
+	e.Evaluate(context.Background(), base.Add(30*time.Second))
+
+	assert.Empty(t, e.Active())
+	assert.Empty(t, rec.states())
+}
+
+func TestEngineRate(t *testing.T) {
+	repo := storage.NewStorage()
+	rules := []Rule{{Name: "Stalled", Type: "counter", Metric: "PollCount", Func: FuncRate, Op: "==", Threshold: 0}}
+	e := NewEngine(repo, rules)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SetCounter("PollCount", 10))
+	// первый замер нужен только как база для rate
+	e.Evaluate(ctx, base)
+	assert.Empty(t, e.Active())
+
+	require.NoError(t, repo.SetCounter("PollCount", 5))
+	e.Evaluate(ctx, base.Add(10*time.Second))
+	assert.Empty(t, e.Active(), "rate is 0.5/s")
+
+	e.Evaluate(ctx, base.Add(20*time.Second))
+	active := e.Active()
+	require.Len(t, active, 1)
+	assert.Equal(t, StateFiring, active[0].State, "zero for means firing immediately")
+	assert.Equal(t, 0.0, active[0].Value)
+}
+
+func TestEngineLabeledSeries(t *testing.T) {
+	rec := &recorder{}
+	hook := httptest.NewServer(rec.handler(t))
+	defer hook.Close()
+
+	repo := storage.NewStorage()
+	rules := []Rule{{Name: "HighHeap", Type: "gauge", Metric: "HeapAlloc", Labels: map[string]string{"env": "prod"}, Op: ">", Threshold: 100}}
+	e := NewEngine(repo, rules, WithNotifier(NewWebhook(hook.URL, nil)))
+	ctx := context.Background()
+
+	require.NoError(t, repo.SetGauge(`HeapAlloc{env="prod",host="web1"}`, 150))
+	require.NoError(t, repo.SetGauge(`HeapAlloc{env="prod",host="web2"}`, 50))
+	require.NoError(t, repo.SetGauge(`HeapAlloc{env="dev",host="web3"}`, 500))
+	e.Evaluate(ctx, base)
+	active := e.Active()
+	require.Len(t, active, 1, "only matching series above the threshold fire")
+	assert.Equal(t, `HeapAlloc{env="prod",host="web1"}`, active[0].Metric)
+	assert.Equal(t, StateFiring, active[0].State)
+
+	require.NoError(t, repo.SetGauge(`HeapAlloc{env="prod",host="web2"}`, 300))
+	e.Evaluate(ctx, base.Add(time.Minute))
+	active = e.Active()
+	require.Len(t, active, 2, "each series has its own alert")
+	assert.Equal(t, `HeapAlloc{env="prod",host="web2"}`, active[1].Metric)
+
+	require.NoError(t, repo.SetGauge(`HeapAlloc{env="prod",host="web1"}`, 10))
+	e.Evaluate(ctx, base.Add(2*time.Minute))
+	active = e.Active()
+	require.Len(t, active, 1)
+	assert.Equal(t, `HeapAlloc{env="prod",host="web2"}`, active[0].Metric)
+	assert.Equal(t, []string{"HighHeap:firing", "HighHeap:firing", "HighHeap:resolved"}, rec.states())
+}
+
+func TestWebhookError(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer hook.Close()
+
+	err := NewWebhook(hook.URL, nil).Notify(context.Background(), []Alert{{Rule: "r", State: StateFiring}})
+	assert.Error(t, err)
+}
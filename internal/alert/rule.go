@@ -0,0 +1,180 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// Функции, применяемые к значению метрики перед сравнением
+const (
+	// FuncValue сравнивает текущее значение
+	FuncValue = ""
+	// FuncRate сравнивает скорость изменения в секунду между двумя вычислениями
+	FuncRate = "rate"
+)
+
+var errInvalidRule = errors.New("invalid alert rule")
+
+// Rule описывает условие срабатывания, например
+//
+//	{"name": "HighHeap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": "500MB", "for": "2m"}
+//	{"name": "AgentStalled", "type": "counter", "metric": "PollCount", "func": "rate", "op": "==", "threshold": 0, "for": "1m"}
+type Rule struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Metric    string            `json:"metric"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Func      string            `json:"func,omitempty"`
+	Op        string            `json:"op"`
+	Threshold Threshold         `json:"threshold"`
+	// For — сколько условие должно выполняться, прежде чем алерт перейдёт из pending в firing
+	For Duration `json:"for,omitempty"`
+}
+
+// LoadRules читает правила из JSON-файла с массивом правил
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules: %w", err)
+	}
+	names := make(map[string]bool, len(rules))
+	for i, r := range rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("rule %d: %w: duplicate name %q", i, errInvalidRule, r.Name)
+		}
+		names[r.Name] = true
+	}
+	return rules, nil
+}
+
+func (r Rule) validate() error {
+	switch {
+	case r.Name == "":
+		return fmt.Errorf("%w: empty name", errInvalidRule)
+	case r.Metric == "":
+		return fmt.Errorf("%w: empty metric", errInvalidRule)
+	case r.Type != models.Gauge && r.Type != models.Counter:
+		return fmt.Errorf("%w: unknown type %q", errInvalidRule, r.Type)
+	case r.Func != FuncValue && r.Func != FuncRate:
+		return fmt.Errorf("%w: unknown func %q", errInvalidRule, r.Func)
+	case r.For < 0:
+		return fmt.Errorf("%w: negative for", errInvalidRule)
+	}
+	if _, ok := operators[r.Op]; !ok {
+		return fmt.Errorf("%w: unknown op %q", errInvalidRule, r.Op)
+	}
+	return nil
+}
+
+// series возвращает значения рядов нужного типа с именем правила и всеми его метками
+func (r Rule) series(gauges map[string]float64, counters map[string]int64) map[string]float64 {
+	matched := make(map[string]float64)
+	match := func(key string) bool {
+		name, labels, err := models.ParseSeriesKey(key)
+		return err == nil && name == r.Metric && models.MatchLabels(labels, r.Labels)
+	}
+	switch r.Type {
+	case models.Gauge:
+		for key, v := range gauges {
+			if match(key) {
+				matched[key] = v
+			}
+		}
+	case models.Counter:
+		for key, v := range counters {
+			if match(key) {
+				matched[key] = float64(v)
+			}
+		}
+	}
+	return matched
+}
+
+var operators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// Threshold — порог правила: число или строка с единицами размера KB, MB, GB (по 1024)
+type Threshold float64
+
+func (t *Threshold) UnmarshalJSON(data []byte) error {
+	var v float64
+	if err := json.Unmarshal(data, &v); err == nil {
+		*t = Threshold(v)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: threshold must be a number or a string", errInvalidRule)
+	}
+	v, err := parseSize(s)
+	if err != nil {
+		return err
+	}
+	*t = Threshold(v)
+	return nil
+}
+
+func parseSize(s string) (float64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	multiplier := 1.0
+	for _, unit := range []struct {
+		suffix string
+		size   float64
+	}{
+		{"KB", 1 << 10},
+		{"MB", 1 << 20},
+		{"GB", 1 << 30},
+		{"TB", 1 << 40},
+		{"B", 1},
+	} {
+		if rest, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, multiplier = strings.TrimSpace(rest), unit.size
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: threshold %q", errInvalidRule, s)
+	}
+	return v * multiplier, nil
+}
+
+// Duration — длительность в формате time.ParseDuration, например "2m"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: duration must be a string like \"2m\"", errInvalidRule)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidRule, err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
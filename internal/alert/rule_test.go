@@ -0,0 +1,74 @@
+package alert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadRules(t *testing.T) {
+	path := writeRules(t, `[
+		{"name": "HighHeap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": "500MB", "for": "2m"},
+		{"name": "Stalled", "type": "counter", "metric": "PollCount", "labels": {"host": "web1"}, "func": "rate", "op": "==", "threshold": 0, "for": "1m"}
+	]`)
+	rules, err := LoadRules(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Name: "HighHeap", Type: "gauge", Metric: "HeapAlloc", Op: ">", Threshold: 500 << 20, For: Duration(2 * time.Minute)},
+		{Name: "Stalled", Type: "counter", Metric: "PollCount", Labels: map[string]string{"host": "web1"}, Func: FuncRate, Op: "==", For: Duration(time.Minute)},
+	}, rules)
+	assert.Equal(t, map[string]float64{`PollCount{agent_id="a",host="web1"}`: 3}, rules[1].series(nil, map[string]int64{
+		`PollCount{agent_id="a",host="web1"}`: 3,
+		`PollCount{host="web2"}`:              1,
+		"PollCount":                           2,
+	}))
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	tests := map[string]string{
+		"bad_json":       `{`,
+		"no_name":        `[{"type": "gauge", "metric": "a", "op": ">", "threshold": 1}]`,
+		"no_metric":      `[{"name": "r", "type": "gauge", "op": ">", "threshold": 1}]`,
+		"bad_type":       `[{"name": "r", "type": "histogram", "metric": "a", "op": ">", "threshold": 1}]`,
+		"bad_op":         `[{"name": "r", "type": "gauge", "metric": "a", "op": "=~", "threshold": 1}]`,
+		"bad_func":       `[{"name": "r", "type": "gauge", "metric": "a", "func": "avg", "op": ">", "threshold": 1}]`,
+		"bad_threshold":  `[{"name": "r", "type": "gauge", "metric": "a", "op": ">", "threshold": "lots"}]`,
+		"bad_for":        `[{"name": "r", "type": "gauge", "metric": "a", "op": ">", "threshold": 1, "for": "soon"}]`,
+		"duplicate_name": `[{"name": "r", "type": "gauge", "metric": "a", "op": ">", "threshold": 1}, {"name": "r", "type": "gauge", "metric": "b", "op": ">", "threshold": 1}]`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadRules(writeRules(t, content))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := LoadRules(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]float64{
+		"500MB":  500 << 20,
+		"1.5 gb": 1.5 * (1 << 30),
+		"10KB":   10 << 10,
+		"42B":    42,
+		"0.25":   0.25,
+	}
+	for in, want := range tests {
+		got, err := parseSize(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+}
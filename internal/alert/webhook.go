@@ -0,0 +1,52 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// webhookPayload — тело запроса вебхука
+type webhookPayload struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+// Webhook отправляет изменившиеся алерты POST-запросом с JSON {"alerts": [...]}
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook создаёт уведомитель; client равный nil заменяется на http.DefaultClient
+func NewWebhook(url string, client *http.Client) *Webhook {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Webhook{url: url, client: client}
+}
+
+func (w *Webhook) Notify(ctx context.Context, alerts []Alert) error {
+	body, err := json.Marshal(webhookPayload{Alerts: alerts})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alerts: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// compressibleTypes перечисляет типы содержимого, которые сервер сжимает в ответах
+var compressibleTypes = []string{
+	"application/json",
+	"text/html",
+}
+
+// Gzip сжимает данные целиком
+func Gzip(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress data: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GzipMiddleware распаковывает тела запросов с Content-Encoding: gzip
+// и сжимает JSON и HTML ответы, если клиент передал Accept-Encoding: gzip.
+func GzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hasToken(r.Header.Get("Content-Encoding"), "gzip") {
+			cr, err := newCompressReader(r.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip body", http.StatusBadRequest)
+				return
+			}
+			defer cr.Close()
+			r.Body = cr
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		}
+
+		if hasToken(r.Header.Get("Accept-Encoding"), "gzip") {
+			cw := newCompressWriter(w)
+			defer cw.Close()
+			w = cw
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasToken проверяет наличие значения в заголовке со списком через запятую
+func hasToken(header, token string) bool {
+	for _, part := range strings.Split(header, ",") {
+		value, _, _ := strings.Cut(part, ";")
+		if strings.EqualFold(strings.TrimSpace(value), token) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCompressible(contentType string) bool {
+	for _, t := range compressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter решает о сжатии по Content-Type в момент отправки заголовков
+type compressWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func newCompressWriter(w http.ResponseWriter) *compressWriter {
+	return &compressWriter{ResponseWriter: w}
+}
+
+func (c *compressWriter) WriteHeader(statusCode int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+	h := c.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if h.Get("Content-Encoding") == "" && isCompressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		c.gz = gzip.NewWriter(c.ResponseWriter)
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if c.gz != nil {
+		return c.gz.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Close дописывает хвост gzip-потока, если ответ сжимался
+func (c *compressWriter) Close() error {
+	if c.gz == nil {
+		return nil
+	}
+	return c.gz.Close()
+}
+
+// compressReader распаковывает тело запроса и закрывает исходный поток
+type compressReader struct {
+	r  io.ReadCloser
+	gz *gzip.Reader
+}
+
+func newCompressReader(r io.ReadCloser) (*compressReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &compressReader{r: r, gz: gz}, nil
+}
+
+func (c *compressReader) Read(p []byte) (int, error) {
+	return c.gz.Read(p)
+}
+
+func (c *compressReader) Close() error {
+	if err := c.r.Close(); err != nil {
+		return err
+	}
+	return c.gz.Close()
+}
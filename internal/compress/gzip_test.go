@@ -0,0 +1,131 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzip(t *testing.T) {
+	data := []byte(`{"id":"test","type":"gauge","value":1}`)
+
+	compressed, err := Gzip(data)
+	require.NoError(t, err)
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	const payload = `{"id":"test","type":"gauge","value":1}`
+
+	echo := func(contentType string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+		})
+	}
+
+	compressed, err := Gzip([]byte(payload))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		contentType     string
+		body            []byte
+		contentEncoding string
+		acceptEncoding  string
+		wantStatus      int
+		wantCompressed  bool
+	}{
+		{
+			name:        "plain_request_plain_response",
+			contentType: "application/json",
+			body:        []byte(payload),
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:            "gzip_request",
+			contentType:     "application/json",
+			body:            compressed,
+			contentEncoding: "gzip",
+			wantStatus:      http.StatusOK,
+		},
+		{
+			name:           "gzip_json_response",
+			contentType:    "application/json",
+			body:           []byte(payload),
+			acceptEncoding: "gzip, deflate",
+			wantStatus:     http.StatusOK,
+			wantCompressed: true,
+		},
+		{
+			name:           "gzip_html_response",
+			contentType:    "text/html; charset=utf-8",
+			body:           []byte(payload),
+			acceptEncoding: "gzip",
+			wantStatus:     http.StatusOK,
+			wantCompressed: true,
+		},
+		{
+			name:           "plain_text_not_compressed",
+			contentType:    "text/plain",
+			body:           []byte(payload),
+			acceptEncoding: "gzip",
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:            "broken_gzip_request",
+			contentType:     "application/json",
+			body:            []byte(payload),
+			contentEncoding: "gzip",
+			wantStatus:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+
+			GzipMiddleware(echo(tt.contentType)).ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			body := w.Body.Bytes()
+			if tt.wantCompressed {
+				assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+				gz, err := gzip.NewReader(bytes.NewReader(body))
+				require.NoError(t, err)
+				body, err = io.ReadAll(gz)
+				require.NoError(t, err)
+			} else {
+				assert.Empty(t, w.Header().Get("Content-Encoding"))
+			}
+			assert.Equal(t, payload, string(body))
+		})
+	}
+}
@@ -1,16 +1,65 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/retry"
 )
 
 type AgentConfig struct {
 	ReportInterval   int
 	PollInterval     int
 	MetricServerHost string
+	// Batch включает отправку всех метрик одним запросом на /updates/
+	Batch bool
+	// CompressMinSize — минимальный размер тела запроса в байтах, начиная с которого агент сжимает его gzip
+	CompressMinSize int
+	// Key — ключ подписи тел запросов HMAC-SHA256, пустое значение отключает подпись
+	Key string
+	// CryptoKey — путь к открытому ключу RSA в PEM для шифрования тел запросов
+	CryptoKey string
+	// RetryDelays — паузы между повторными попытками при временных ошибках
+	RetryDelays []time.Duration
+	// RateLimit — максимальное число одновременных исходящих запросов
+	RateLimit int
+	// Collectors — имена включённых коллекторов, пустой список включает все встроенные
+	Collectors []string
+	// DisabledCollectors — имена коллекторов, которые нужно отключить
+	DisabledCollectors []string
+	// DiskMounts — точки монтирования для коллектора disk, пустой список означает все устройства
+	DiskMounts []string
+	// Processes — PID или имена процессов для коллектора process
+	Processes []string
+	// AgentID — идентификатор агента в метке agent_id, пустое значение — имя хоста
+	AgentID string
+	// Labels — статические метки, которые агент добавляет ко всем метрикам
+	Labels map[string]string
+	// Transport — способ отправки метрик: http (метрика в пути или пакет при Batch), json, grpc,
+	// stdout или file для отладки
+	Transport string
+	// OutputPath — файл, в который пишет транспорт file
+	OutputPath string
+	// TLSCA — набор CA в PEM для проверки сертификата сервера вместо системного
+	TLSCA string
+	// TLSCert и TLSKey — клиентский сертификат и ключ агента в PEM для mTLS
+	TLSCert string
+	TLSKey  string
+	// TLSInsecure отключает проверку сертификата сервера, только для отладки
+	TLSInsecure bool
+	// GRPCAddress — адрес gRPC-сервера для транспорта grpc
+	GRPCAddress string
+	// LogLevel — минимальный уровень логирования: debug, info, warn или error
+	LogLevel string
+	// LogJSON включает вывод логов в JSON
+	LogJSON bool
 }
 
 func NewAgentConfig() *AgentConfig {
@@ -18,6 +67,12 @@ func NewAgentConfig() *AgentConfig {
 		PollInterval:     2,
 		ReportInterval:   10,
 		MetricServerHost: "localhost:8080",
+		CompressMinSize:  1024,
+		RetryDelays:      slices.Clone(retry.DefaultDelays),
+		RateLimit:        1,
+		LogLevel:         "info",
+		Transport:        "http",
+		GRPCAddress:      "localhost:3200",
 	}
 }
 
@@ -26,7 +81,33 @@ func ParseAgentFlags() (*AgentConfig, error) {
 
 	flag.IntVar(&cfg.PollInterval, "p", 2, "poll interval seconds")
 	flag.IntVar(&cfg.ReportInterval, "r", 10, "report interval seconds")
-	flag.StringVar(&cfg.MetricServerHost, "a", cfg.MetricServerHost, "server address")
+	flag.StringVar(&cfg.MetricServerHost, "a", cfg.MetricServerHost, "server address, host:port or http(s)://host:port")
+	flag.BoolVar(&cfg.Batch, "b", cfg.Batch, "send metrics in a single batch request")
+	flag.IntVar(&cfg.CompressMinSize, "compress-min-size", cfg.CompressMinSize, "minimal request body size in bytes to compress with gzip")
+	flag.StringVar(&cfg.Key, "k", cfg.Key, "HMAC-SHA256 signing key")
+	flag.StringVar(&cfg.CryptoKey, "crypto-key", cfg.CryptoKey, "RSA public key PEM path")
+	flag.IntVar(&cfg.RateLimit, "l", cfg.RateLimit, "max concurrent outgoing requests")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level: debug, info, warn or error")
+	flag.BoolVar(&cfg.LogJSON, "log-json", cfg.LogJSON, "write logs as JSON")
+	retryDelays := formatDurations(cfg.RetryDelays)
+	flag.StringVar(&retryDelays, "retry-delays", retryDelays, "comma-separated backoff delays for retriable errors")
+
+	flag.StringVar(&cfg.Transport, "transport", cfg.Transport, "metrics transport: http, json, grpc, stdout or file")
+	flag.StringVar(&cfg.OutputPath, "output", cfg.OutputPath, "output file for the file transport")
+	flag.StringVar(&cfg.TLSCA, "tls-ca", cfg.TLSCA, "CA bundle PEM path to verify the server certificate")
+	flag.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "client TLS certificate PEM path")
+	flag.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "client TLS private key PEM path")
+	flag.BoolVar(&cfg.TLSInsecure, "tls-insecure", cfg.TLSInsecure, "skip server certificate verification")
+	flag.StringVar(&cfg.GRPCAddress, "grpc-address", cfg.GRPCAddress, "gRPC server address for the grpc transport")
+	var collectors, disabledCollectors string
+	flag.StringVar(&collectors, "collectors", "", "comma-separated list of enabled collectors, empty means all")
+	flag.StringVar(&disabledCollectors, "disable-collectors", "", "comma-separated list of disabled collectors")
+	flag.StringVar(&cfg.AgentID, "agent-id", cfg.AgentID, "agent identifier sent in the agent_id label")
+	var labels string
+	flag.StringVar(&labels, "labels", "", "comma-separated static labels key=value attached to every metric")
+	var diskMounts, processes string
+	flag.StringVar(&diskMounts, "disk-mounts", "", "comma-separated mountpoints for the disk collector, empty means all devices")
+	flag.StringVar(&processes, "processes", "", "comma-separated PIDs or process names for the process collector")
 
 	flag.Parse()
 
@@ -34,6 +115,10 @@ func ParseAgentFlags() (*AgentConfig, error) {
 	if envADDRESS != "" {
 		cfg.MetricServerHost = envADDRESS
 	}
+	if err := validateServerURL(cfg.MetricServerHost); err != nil {
+		fmt.Println("Ошибка address:", err)
+		return nil, err
+	}
 	envReportInterval := os.Getenv("REPORT_INTERVAL")
 	if envReportInterval != "" {
 		r, err := strconv.Atoi(envReportInterval)
@@ -55,5 +140,212 @@ func ParseAgentFlags() (*AgentConfig, error) {
 		cfg.PollInterval = p
 	}
 
+	envBatch := os.Getenv("BATCH")
+	if envBatch != "" {
+		b, err := strconv.ParseBool(envBatch)
+		if err != nil {
+			fmt.Println("Ошибка env BATCH:", err)
+			return nil, err
+		}
+		cfg.Batch = b
+	}
+
+	envCompressMinSize := os.Getenv("COMPRESS_MIN_SIZE")
+	if envCompressMinSize != "" {
+		c, err := strconv.Atoi(envCompressMinSize)
+		if err != nil {
+			fmt.Println("Ошибка env COMPRESS_MIN_SIZE:", err)
+			return nil, err
+		}
+		cfg.CompressMinSize = c
+	}
+
+	envKey := os.Getenv("KEY")
+	if envKey != "" {
+		cfg.Key = envKey
+	}
+
+	envCryptoKey := os.Getenv("CRYPTO_KEY")
+	if envCryptoKey != "" {
+		cfg.CryptoKey = envCryptoKey
+	}
+
+	envRateLimit := os.Getenv("RATE_LIMIT")
+	if envRateLimit != "" {
+		l, err := strconv.Atoi(envRateLimit)
+		if err != nil {
+			fmt.Println("Ошибка env RATE_LIMIT:", err)
+			return nil, err
+		}
+		cfg.RateLimit = l
+	}
+
+	envTransport := os.Getenv("TRANSPORT")
+	if envTransport != "" {
+		cfg.Transport = envTransport
+	}
+	envOutputPath := os.Getenv("OUTPUT_PATH")
+	if envOutputPath != "" {
+		cfg.OutputPath = envOutputPath
+	}
+	if err := validateTransport(cfg); err != nil {
+		fmt.Println("Ошибка transport:", err)
+		return nil, err
+	}
+
+	envGRPCAddress := os.Getenv("GRPC_ADDRESS")
+	if envGRPCAddress != "" {
+		cfg.GRPCAddress = envGRPCAddress
+	}
+
+	envTLSCA := os.Getenv("TLS_CA")
+	if envTLSCA != "" {
+		cfg.TLSCA = envTLSCA
+	}
+
+	envTLSCert := os.Getenv("TLS_CERT")
+	if envTLSCert != "" {
+		cfg.TLSCert = envTLSCert
+	}
+
+	envTLSKey := os.Getenv("TLS_KEY")
+	if envTLSKey != "" {
+		cfg.TLSKey = envTLSKey
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		err := errors.New("TLS certificate and key must be set together")
+		fmt.Println("Ошибка TLS:", err)
+		return nil, err
+	}
+
+	envTLSInsecure := os.Getenv("TLS_INSECURE")
+	if envTLSInsecure != "" {
+		b, err := strconv.ParseBool(envTLSInsecure)
+		if err != nil {
+			fmt.Println("Ошибка env TLS_INSECURE:", err)
+			return nil, err
+		}
+		cfg.TLSInsecure = b
+	}
+	// при включённом TLS адрес без схемы означает https, а явный http:// — ошибка конфигурации
+	if cfg.TLSEnabled() {
+		if strings.HasPrefix(cfg.MetricServerHost, "http://") {
+			err := errors.New("TLS options require an https:// server address")
+			fmt.Println("Ошибка TLS:", err)
+			return nil, err
+		}
+		if !strings.Contains(cfg.MetricServerHost, "://") {
+			cfg.MetricServerHost = "https://" + cfg.MetricServerHost
+		}
+	}
+
+	envLogLevel := os.Getenv("LOG_LEVEL")
+	if envLogLevel != "" {
+		cfg.LogLevel = envLogLevel
+	}
+
+	envLogJSON := os.Getenv("LOG_JSON")
+	if envLogJSON != "" {
+		j, err := strconv.ParseBool(envLogJSON)
+		if err != nil {
+			fmt.Println("Ошибка env LOG_JSON:", err)
+			return nil, err
+		}
+		cfg.LogJSON = j
+	}
+
+	envRetryDelays, ok := os.LookupEnv("RETRY_DELAYS")
+	if ok {
+		retryDelays = envRetryDelays
+	}
+	delays, err := parseDurations(retryDelays)
+	if err != nil {
+		fmt.Println("Ошибка retry delays:", err)
+		return nil, err
+	}
+	cfg.RetryDelays = delays
+
+	envCollectors, ok := os.LookupEnv("COLLECTORS")
+	if ok {
+		collectors = envCollectors
+	}
+	cfg.Collectors = parseList(collectors)
+
+	envDisabledCollectors, ok := os.LookupEnv("DISABLE_COLLECTORS")
+	if ok {
+		disabledCollectors = envDisabledCollectors
+	}
+	cfg.DisabledCollectors = parseList(disabledCollectors)
+
+	envDiskMounts, ok := os.LookupEnv("DISK_MOUNTS")
+	if ok {
+		diskMounts = envDiskMounts
+	}
+	cfg.DiskMounts = parseList(diskMounts)
+
+	envProcesses, ok := os.LookupEnv("PROCESSES")
+	if ok {
+		processes = envProcesses
+	}
+	cfg.Processes = parseList(processes)
+
+	envAgentID := os.Getenv("AGENT_ID")
+	if envAgentID != "" {
+		cfg.AgentID = envAgentID
+	}
+
+	envLabels, ok := os.LookupEnv("LABELS")
+	if ok {
+		labels = envLabels
+	}
+	cfg.Labels, err = parseLabels(labels)
+	if err != nil {
+		fmt.Println("Ошибка labels:", err)
+		return nil, err
+	}
+
 	return cfg, nil
 }
+
+// TLSEnabled сообщает, нужен ли TLS: сервер задан адресом https:// или заданы параметры TLS
+func (c *AgentConfig) TLSEnabled() bool {
+	return strings.HasPrefix(c.MetricServerHost, "https://") || c.TLSCA != "" || c.TLSCert != "" || c.TLSInsecure
+}
+
+// validateTransport проверяет имя транспорта и его параметры
+func validateTransport(cfg *AgentConfig) error {
+	switch cfg.Transport {
+	case "http", "json", "stdout":
+		return nil
+	case "grpc":
+		// gRPC-транспорт не шифрует сообщения ключом сервера, для него нужен TLS
+		if cfg.CryptoKey != "" {
+			return errors.New("grpc transport does not support crypto key, use TLS instead")
+		}
+		return nil
+	case "file":
+		if cfg.OutputPath == "" {
+			return errors.New("file transport requires an output path")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown transport %q", cfg.Transport)
+}
+
+// validateServerURL допускает адрес host:port или URL со схемой http либо https
+func validateServerURL(address string) error {
+	if !strings.Contains(address, "://") {
+		return nil
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host in %q", address)
+	}
+	return nil
+}
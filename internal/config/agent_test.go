@@ -4,7 +4,9 @@ import (
 	"flag"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/iudanet/yp-metrics-go/internal/retry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,6 +16,9 @@ func TestNewAgentConfig(t *testing.T) {
 	assert.Equal(t, 2, cfg.PollInterval, "default poll interval should be 2")
 	assert.Equal(t, 10, cfg.ReportInterval, "default report interval should be 10")
 	assert.Equal(t, "localhost:8080", cfg.MetricServerHost, "default address should be localhost:8080")
+	assert.Equal(t, 1024, cfg.CompressMinSize, "default compress min size should be 1024")
+	assert.Equal(t, retry.DefaultDelays, cfg.RetryDelays, "default retry delays should be 1s,3s,5s")
+	assert.Equal(t, 1, cfg.RateLimit, "default rate limit should be 1")
 }
 
 func TestParseAgentFlags(t *testing.T) {
@@ -41,6 +46,12 @@ func TestParseAgentFlags(t *testing.T) {
 				PollInterval:     2,
 				ReportInterval:   10,
 				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
 			},
 		},
 		{
@@ -50,6 +61,12 @@ func TestParseAgentFlags(t *testing.T) {
 				PollInterval:     5,
 				ReportInterval:   15,
 				MetricServerHost: "localhost:9090",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
 			},
 		},
 		{
@@ -64,6 +81,12 @@ func TestParseAgentFlags(t *testing.T) {
 				PollInterval:     3,
 				ReportInterval:   20,
 				MetricServerHost: "localhost:7070",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
 			},
 		},
 		{
@@ -78,8 +101,404 @@ func TestParseAgentFlags(t *testing.T) {
 				PollInterval:     3,
 				ReportInterval:   20,
 				MetricServerHost: "localhost:7070",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
 			},
 		},
+		{
+			name: "batch_mode",
+			args: []string{programName, "-b"},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				Batch:            true,
+			},
+		},
+		{
+			name: "batch_mode_env",
+			args: []string{programName},
+			envVars: map[string]string{
+				"BATCH": "true",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				Batch:            true,
+			},
+		},
+		{
+			name: "compress_min_size",
+			args: []string{programName, "-compress-min-size", "64"},
+			envVars: map[string]string{
+				"COMPRESS_MIN_SIZE": "128",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  128,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+			},
+		},
+		{
+			name: "key",
+			args: []string{programName, "-k", "flag-key"},
+			envVars: map[string]string{
+				"KEY": "env-key",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				Key:              "env-key",
+			},
+		},
+		{
+			name: "crypto_key",
+			args: []string{programName, "-crypto-key", "/tmp/flag.pem"},
+			envVars: map[string]string{
+				"CRYPTO_KEY": "/etc/metrics/public.pem",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				CryptoKey:        "/etc/metrics/public.pem",
+			},
+		},
+		{
+			name: "retry_delays",
+			args: []string{programName, "-retry-delays", "2s,4s"},
+			envVars: map[string]string{
+				"RETRY_DELAYS": "100ms, 200ms",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+			},
+		},
+		{
+			name: "retry_disabled",
+			args: []string{programName, "-retry-delays", ""},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+			},
+		},
+		{
+			name: "invalid_retry_delays",
+			args: []string{programName},
+			envVars: map[string]string{
+				"RETRY_DELAYS": "1s,soon",
+			},
+			expectedError: true,
+		},
+		{
+			name: "rate_limit",
+			args: []string{programName, "-l", "4"},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        4,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+			},
+		},
+		{
+			name: "rate_limit_env",
+			args: []string{programName, "-l", "4"},
+			envVars: map[string]string{
+				"RATE_LIMIT": "8",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        8,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+			},
+		},
+		{
+			name: "collectors_flags",
+			args: []string{programName, "-collectors", "runtime, system", "-disable-collectors", "random"},
+			expected: &AgentConfig{
+				PollInterval:       2,
+				ReportInterval:     10,
+				MetricServerHost:   "localhost:8080",
+				CompressMinSize:    1024,
+				RetryDelays:        retry.DefaultDelays,
+				RateLimit:          1,
+				LogLevel:           "info",
+				Transport:          "http",
+				GRPCAddress:        "localhost:3200",
+				Collectors:         []string{"runtime", "system"},
+				DisabledCollectors: []string{"random"},
+			},
+		},
+		{
+			name: "collectors_env_override",
+			args: []string{programName, "-collectors", "runtime"},
+			envVars: map[string]string{
+				"COLLECTORS":         "pollcount,random",
+				"DISABLE_COLLECTORS": "system",
+			},
+			expected: &AgentConfig{
+				PollInterval:       2,
+				ReportInterval:     10,
+				MetricServerHost:   "localhost:8080",
+				CompressMinSize:    1024,
+				RetryDelays:        retry.DefaultDelays,
+				RateLimit:          1,
+				LogLevel:           "info",
+				Transport:          "http",
+				GRPCAddress:        "localhost:3200",
+				Collectors:         []string{"pollcount", "random"},
+				DisabledCollectors: []string{"system"},
+			},
+		},
+		{
+			name: "process_and_disk_targets",
+			args: []string{programName, "-disk-mounts", "/,/var/lib", "-processes", "nginx"},
+			envVars: map[string]string{
+				"PROCESSES": "42,postgres",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				DiskMounts:       []string{"/", "/var/lib"},
+				Processes:        []string{"42", "postgres"},
+			},
+		},
+		{
+			name: "agent_id_and_labels",
+			args: []string{programName, "-agent-id", "agent-1", "-labels", "env=dev"},
+			envVars: map[string]string{
+				"LABELS": "env=prod, dc=eu",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				AgentID:          "agent-1",
+				Labels:           map[string]string{"env": "prod", "dc": "eu"},
+			},
+		},
+		{
+			name:          "invalid_labels",
+			args:          []string{programName, "-labels", "env"},
+			expectedError: true,
+		},
+		{
+			name: "grpc_transport",
+			args: []string{programName, "-transport", "grpc"},
+			envVars: map[string]string{
+				"GRPC_ADDRESS": "metrics:3200",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "grpc",
+				GRPCAddress:      "metrics:3200",
+			},
+		},
+		{
+			name: "file_transport_https_address",
+			args: []string{programName, "-transport", "file", "-a", "https://metrics.example.com"},
+			envVars: map[string]string{
+				"OUTPUT_PATH": "/tmp/metrics.jsonl",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "https://metrics.example.com",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "file",
+				OutputPath:       "/tmp/metrics.jsonl",
+				GRPCAddress:      "localhost:3200",
+			},
+		},
+		{
+			name:          "file_transport_without_output",
+			args:          []string{programName, "-transport", "file"},
+			expectedError: true,
+		},
+		{
+			name:          "unsupported_address_scheme",
+			args:          []string{programName, "-a", "ftp://localhost:8080"},
+			expectedError: true,
+		},
+		{
+			name: "mutual_tls",
+			args: []string{programName, "-a", "https://localhost:8443", "-tls-ca", "ca.pem", "-tls-cert", "agent.crt", "-tls-key", "agent.key"},
+			envVars: map[string]string{
+				"TLS_INSECURE": "true",
+			},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "https://localhost:8443",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				TLSCA:            "ca.pem",
+				TLSCert:          "agent.crt",
+				TLSKey:           "agent.key",
+				TLSInsecure:      true,
+			},
+		},
+		{
+			name: "tls_ca_without_scheme",
+			args: []string{programName, "-a", "localhost:8443", "-tls-ca", "ca.pem"},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "https://localhost:8443",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "info",
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+				TLSCA:            "ca.pem",
+			},
+		},
+		{
+			name:          "tls_ca_with_http_address",
+			args:          []string{programName, "-a", "http://localhost:8443", "-tls-ca", "ca.pem"},
+			expectedError: true,
+		},
+		{
+			name:          "tls_key_without_cert",
+			args:          []string{programName, "-tls-key", "agent.key"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid_tls_insecure",
+			args:          []string{programName},
+			envVars:       map[string]string{"TLS_INSECURE": "maybe"},
+			expectedError: true,
+		},
+		{
+			name: "json_logs",
+			args: []string{programName, "-log-level", "debug", "-log-json"},
+			expected: &AgentConfig{
+				PollInterval:     2,
+				ReportInterval:   10,
+				MetricServerHost: "localhost:8080",
+				CompressMinSize:  1024,
+				RetryDelays:      retry.DefaultDelays,
+				RateLimit:        1,
+				LogLevel:         "debug",
+				LogJSON:          true,
+				Transport:        "http",
+				GRPCAddress:      "localhost:3200",
+			},
+		},
+		{
+			name:          "grpc_transport_with_crypto_key",
+			args:          []string{programName, "-transport", "grpc", "-crypto-key", "public.pem"},
+			expectedError: true,
+		},
+		{
+			name:          "invalid_transport",
+			args:          []string{programName, "-transport", "carrier-pigeon"},
+			expectedError: true,
+		},
+		{
+			name: "invalid_rate_limit",
+			args: []string{programName},
+			envVars: map[string]string{
+				"RATE_LIMIT": "many",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid_batch",
+			args: []string{programName},
+			envVars: map[string]string{
+				"BATCH": "invalid",
+			},
+			expectedError: true,
+		},
 		{
 			name: "invalid_report_interval",
 			args: []string{programName},
@@ -107,6 +526,27 @@ func TestParseAgentFlags(t *testing.T) {
 			os.Unsetenv("ADDRESS")
 			os.Unsetenv("REPORT_INTERVAL")
 			os.Unsetenv("POLL_INTERVAL")
+			os.Unsetenv("BATCH")
+			os.Unsetenv("COMPRESS_MIN_SIZE")
+			os.Unsetenv("KEY")
+			os.Unsetenv("CRYPTO_KEY")
+			os.Unsetenv("RETRY_DELAYS")
+			os.Unsetenv("RATE_LIMIT")
+			os.Unsetenv("COLLECTORS")
+			os.Unsetenv("DISABLE_COLLECTORS")
+			os.Unsetenv("DISK_MOUNTS")
+			os.Unsetenv("PROCESSES")
+			os.Unsetenv("AGENT_ID")
+			os.Unsetenv("LABELS")
+			os.Unsetenv("TRANSPORT")
+			os.Unsetenv("GRPC_ADDRESS")
+			os.Unsetenv("OUTPUT_PATH")
+			os.Unsetenv("TLS_CA")
+			os.Unsetenv("TLS_CERT")
+			os.Unsetenv("TLS_KEY")
+			os.Unsetenv("TLS_INSECURE")
+			os.Unsetenv("LOG_LEVEL")
+			os.Unsetenv("LOG_JSON")
 
 			// Устанавливаем тестовые переменные окружения
 			for k, v := range tt.envVars {
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parseDurations разбирает список длительностей через запятую, например "1s,3s,5s".
+// Пустая строка означает пустой список.
+func parseDurations(s string) ([]time.Duration, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	durations := make([]time.Duration, 0, len(parts))
+	for _, part := range parts {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("negative duration %s", d)
+		}
+		durations = append(durations, d)
+	}
+	return durations, nil
+}
+
+// formatDurations — обратное к parseDurations преобразование для значений флагов по умолчанию
+func formatDurations(durations []time.Duration) string {
+	parts := make([]string, len(durations))
+	for i, d := range durations {
+		parts[i] = d.String()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// parseList разбирает список значений через запятую, пропуская пустые элементы
+func parseList(s string) []string {
+	var list []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// parseLabels разбирает метки вида "env=prod,dc=eu"
+func parseLabels(s string) (map[string]string, error) {
+	items := parseList(s)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(items))
+	for _, item := range items {
+		k, v, ok := strings.Cut(item, "=")
+		k = strings.TrimSpace(k)
+		if !ok || !models.ValidLabelName(k) {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", item)
+		}
+		labels[k] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
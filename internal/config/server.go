@@ -1,29 +1,277 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
+	"net"
 	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/retry"
 )
 
 type ServerConfig struct {
 	MetricServerHost string
+	// StoreInterval — интервал сохранения метрик в файл в секундах, 0 — синхронная запись
+	StoreInterval int
+	// FileStoragePath — путь к файлу с метриками, пустое значение отключает сохранение
+	FileStoragePath string
+	// Restore — загружать ли метрики из файла при старте
+	Restore bool
+	// DatabaseDSN — строка подключения к PostgreSQL, при заполнении метрики хранятся в базе
+	DatabaseDSN string
+	// SQLitePath — путь к файлу базы SQLite для однонодовых установок
+	SQLitePath string
+	// Key — общий с агентами ключ подписи HMAC-SHA256, пустое значение отключает проверку
+	Key string
+	// CryptoKey — путь к закрытому ключу RSA в PEM для расшифровки тел запросов агентов
+	CryptoKey string
+	// RetryDelays — паузы между повторными попытками при временных ошибках
+	RetryDelays []time.Duration
+	// ShutdownTimeout — сколько секунд ждать завершения активных запросов при остановке
+	ShutdownTimeout int
+	// StatsdAddress — UDP-адрес приёма метрик в формате StatsD, пустое значение отключает приём
+	StatsdAddress string
+	// HistorySize — сколько последних значений каждого ряда хранить для /history/, 0 отключает историю
+	HistorySize int
+	// HistoryRetention — сколько секунд хранить значения истории, 0 — без ограничения по возрасту
+	HistoryRetention int
+	// AlertRules — путь к JSON-файлу правил алертов, пустое значение отключает алерты
+	AlertRules string
+	// AlertWebhook — URL, на который отправляются сработавшие и снятые алерты
+	AlertWebhook string
+	// AlertInterval — интервал проверки правил алертов в секундах
+	AlertInterval int
+	// GRPCAddress — адрес gRPC-сервера, пустое значение отключает gRPC
+	GRPCAddress string
+	// TrustedSubnet — подсеть в нотации CIDR, из которой принимаются обновления метрик; пустое значение отключает проверку
+	TrustedSubnet string
+	// TLSCert и TLSKey — сертификат и закрытый ключ сервера в PEM, при заполнении HTTP и gRPC работают по TLS
+	TLSCert string
+	TLSKey  string
+	// TLSClientCA — набор CA в PEM для проверки клиентских сертификатов агентов (mTLS)
+	TLSClientCA string
+	// LogLevel — минимальный уровень логирования: debug, info, warn или error
+	LogLevel string
+	// LogJSON включает вывод логов в JSON
+	LogJSON bool
 }
 
 func NewServerConfig() *ServerConfig {
 	return &ServerConfig{
 		MetricServerHost: "localhost:8080",
+		StoreInterval:    300,
+		Restore:          true,
+		RetryDelays:      slices.Clone(retry.DefaultDelays),
+		ShutdownTimeout:  10,
+		HistoryRetention: 3600,
+		AlertInterval:    10,
+		LogLevel:         "info",
 	}
 }
 
-func ParseServerFlags() *ServerConfig {
+func ParseServerFlags() (*ServerConfig, error) {
 	cfg := NewServerConfig()
 
 	flag.StringVar(&cfg.MetricServerHost, "a", cfg.MetricServerHost, "server address")
+	flag.IntVar(&cfg.StoreInterval, "i", cfg.StoreInterval, "store interval seconds, 0 for synchronous writes")
+	flag.StringVar(&cfg.FileStoragePath, "f", cfg.FileStoragePath, "metrics file path")
+	flag.BoolVar(&cfg.Restore, "r", cfg.Restore, "restore metrics from file on start")
+	flag.StringVar(&cfg.DatabaseDSN, "d", cfg.DatabaseDSN, "PostgreSQL DSN")
+	flag.StringVar(&cfg.SQLitePath, "sqlite", cfg.SQLitePath, "SQLite database file path")
+	flag.StringVar(&cfg.Key, "k", cfg.Key, "HMAC-SHA256 signing key")
+	flag.StringVar(&cfg.CryptoKey, "crypto-key", cfg.CryptoKey, "RSA private key PEM path")
+	flag.IntVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "graceful shutdown timeout seconds")
+	flag.StringVar(&cfg.StatsdAddress, "statsd", cfg.StatsdAddress, "UDP address of the StatsD listener, empty to disable")
+	flag.IntVar(&cfg.HistorySize, "history-size", cfg.HistorySize, "samples kept per series for /history/, 0 to disable")
+	flag.IntVar(&cfg.HistoryRetention, "history-retention", cfg.HistoryRetention, "history retention seconds, 0 for no age limit")
+	flag.StringVar(&cfg.AlertRules, "alert-rules", cfg.AlertRules, "alert rules JSON file path, empty to disable alerting")
+	flag.StringVar(&cfg.AlertWebhook, "alert-webhook", cfg.AlertWebhook, "webhook URL for firing and resolved alerts")
+	flag.IntVar(&cfg.AlertInterval, "alert-interval", cfg.AlertInterval, "alert rules evaluation interval seconds")
+	flag.StringVar(&cfg.GRPCAddress, "grpc-address", cfg.GRPCAddress, "gRPC server address, empty to disable")
+	flag.StringVar(&cfg.TrustedSubnet, "t", cfg.TrustedSubnet, "trusted subnet CIDR for metric updates")
+	flag.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "TLS certificate PEM path")
+	flag.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "TLS private key PEM path")
+	flag.StringVar(&cfg.TLSClientCA, "tls-client-ca", cfg.TLSClientCA, "CA bundle PEM path to verify agent certificates")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level: debug, info, warn or error")
+	flag.BoolVar(&cfg.LogJSON, "log-json", cfg.LogJSON, "write logs as JSON")
+	retryDelays := formatDurations(cfg.RetryDelays)
+	flag.StringVar(&retryDelays, "retry-delays", retryDelays, "comma-separated backoff delays for transient storage errors")
 	flag.Parse()
 	envADDRESS := os.Getenv("ADDRESS")
 	if envADDRESS != "" {
 		cfg.MetricServerHost = envADDRESS
 	}
 
-	return cfg
+	envStoreInterval := os.Getenv("STORE_INTERVAL")
+	if envStoreInterval != "" {
+		i, err := strconv.Atoi(envStoreInterval)
+		if err != nil {
+			fmt.Println("Ошибка env STORE_INTERVAL:", err)
+			return nil, err
+		}
+		cfg.StoreInterval = i
+	}
+
+	envFileStoragePath, ok := os.LookupEnv("FILE_STORAGE_PATH")
+	if ok {
+		cfg.FileStoragePath = envFileStoragePath
+	}
+
+	envRestore := os.Getenv("RESTORE")
+	if envRestore != "" {
+		r, err := strconv.ParseBool(envRestore)
+		if err != nil {
+			fmt.Println("Ошибка env RESTORE:", err)
+			return nil, err
+		}
+		cfg.Restore = r
+	}
+
+	envDatabaseDSN := os.Getenv("DATABASE_DSN")
+	if envDatabaseDSN != "" {
+		cfg.DatabaseDSN = envDatabaseDSN
+	}
+
+	envSQLitePath := os.Getenv("SQLITE_PATH")
+	if envSQLitePath != "" {
+		cfg.SQLitePath = envSQLitePath
+	}
+
+	envKey := os.Getenv("KEY")
+	if envKey != "" {
+		cfg.Key = envKey
+	}
+
+	envCryptoKey := os.Getenv("CRYPTO_KEY")
+	if envCryptoKey != "" {
+		cfg.CryptoKey = envCryptoKey
+	}
+
+	envShutdownTimeout := os.Getenv("SHUTDOWN_TIMEOUT")
+	if envShutdownTimeout != "" {
+		t, err := strconv.Atoi(envShutdownTimeout)
+		if err != nil {
+			fmt.Println("Ошибка env SHUTDOWN_TIMEOUT:", err)
+			return nil, err
+		}
+		cfg.ShutdownTimeout = t
+	}
+
+	envStatsdAddress := os.Getenv("STATSD_ADDRESS")
+	if envStatsdAddress != "" {
+		cfg.StatsdAddress = envStatsdAddress
+	}
+
+	envHistorySize := os.Getenv("HISTORY_SIZE")
+	if envHistorySize != "" {
+		h, err := strconv.Atoi(envHistorySize)
+		if err != nil {
+			fmt.Println("Ошибка env HISTORY_SIZE:", err)
+			return nil, err
+		}
+		cfg.HistorySize = h
+	}
+
+	envHistoryRetention := os.Getenv("HISTORY_RETENTION")
+	if envHistoryRetention != "" {
+		h, err := strconv.Atoi(envHistoryRetention)
+		if err != nil {
+			fmt.Println("Ошибка env HISTORY_RETENTION:", err)
+			return nil, err
+		}
+		cfg.HistoryRetention = h
+	}
+
+	envAlertRules := os.Getenv("ALERT_RULES")
+	if envAlertRules != "" {
+		cfg.AlertRules = envAlertRules
+	}
+
+	envAlertWebhook := os.Getenv("ALERT_WEBHOOK")
+	if envAlertWebhook != "" {
+		cfg.AlertWebhook = envAlertWebhook
+	}
+
+	envAlertInterval := os.Getenv("ALERT_INTERVAL")
+	if envAlertInterval != "" {
+		a, err := strconv.Atoi(envAlertInterval)
+		if err != nil {
+			fmt.Println("Ошибка env ALERT_INTERVAL:", err)
+			return nil, err
+		}
+		cfg.AlertInterval = a
+	}
+
+	envGRPCAddress := os.Getenv("GRPC_ADDRESS")
+	if envGRPCAddress != "" {
+		cfg.GRPCAddress = envGRPCAddress
+	}
+
+	envTrustedSubnet := os.Getenv("TRUSTED_SUBNET")
+	if envTrustedSubnet != "" {
+		cfg.TrustedSubnet = envTrustedSubnet
+	}
+	if cfg.TrustedSubnet != "" {
+		if _, _, err := net.ParseCIDR(cfg.TrustedSubnet); err != nil {
+			fmt.Println("Ошибка trusted subnet:", err)
+			return nil, err
+		}
+	}
+
+	envTLSCert := os.Getenv("TLS_CERT")
+	if envTLSCert != "" {
+		cfg.TLSCert = envTLSCert
+	}
+
+	envTLSKey := os.Getenv("TLS_KEY")
+	if envTLSKey != "" {
+		cfg.TLSKey = envTLSKey
+	}
+
+	envTLSClientCA := os.Getenv("TLS_CLIENT_CA")
+	if envTLSClientCA != "" {
+		cfg.TLSClientCA = envTLSClientCA
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		err := errors.New("TLS certificate and key must be set together")
+		fmt.Println("Ошибка TLS:", err)
+		return nil, err
+	}
+	if cfg.TLSClientCA != "" && cfg.TLSCert == "" {
+		err := errors.New("client CA requires a TLS certificate")
+		fmt.Println("Ошибка TLS:", err)
+		return nil, err
+	}
+
+	envLogLevel := os.Getenv("LOG_LEVEL")
+	if envLogLevel != "" {
+		cfg.LogLevel = envLogLevel
+	}
+
+	envLogJSON := os.Getenv("LOG_JSON")
+	if envLogJSON != "" {
+		j, err := strconv.ParseBool(envLogJSON)
+		if err != nil {
+			fmt.Println("Ошибка env LOG_JSON:", err)
+			return nil, err
+		}
+		cfg.LogJSON = j
+	}
+
+	envRetryDelays, ok := os.LookupEnv("RETRY_DELAYS")
+	if ok {
+		retryDelays = envRetryDelays
+	}
+	delays, err := parseDurations(retryDelays)
+	if err != nil {
+		fmt.Println("Ошибка retry delays:", err)
+		return nil, err
+	}
+	cfg.RetryDelays = delays
+
+	return cfg, nil
 }
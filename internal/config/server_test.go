@@ -1,9 +1,12 @@
 package config
 
 import (
+	"flag"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/iudanet/yp-metrics-go/internal/retry"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -11,6 +14,10 @@ func TestNewServerConfig(t *testing.T) {
 	cfg := NewServerConfig()
 
 	assert.Equal(t, "localhost:8080", cfg.MetricServerHost, "default address should be localhost:8080")
+	assert.Equal(t, 300, cfg.StoreInterval, "default store interval should be 300")
+	assert.Empty(t, cfg.FileStoragePath, "file storage should be disabled by default")
+	assert.True(t, cfg.Restore, "restore should be enabled by default")
+	assert.Equal(t, 10, cfg.ShutdownTimeout, "default shutdown timeout should be 10")
 }
 
 func TestParseServerFlags_Environment(t *testing.T) {
@@ -34,6 +41,13 @@ func TestParseServerFlags_Environment(t *testing.T) {
 			},
 			expected: ServerConfig{
 				MetricServerHost: "localhost:9090",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
 			},
 		},
 		{
@@ -41,6 +55,13 @@ func TestParseServerFlags_Environment(t *testing.T) {
 			envVars: map[string]string{},
 			expected: ServerConfig{
 				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
 			},
 		},
 	}
@@ -65,3 +86,369 @@ func TestParseServerFlags_Environment(t *testing.T) {
 		})
 	}
 }
+
+func TestParseServerFlags(t *testing.T) {
+	oldArgs := os.Args
+	oldFlagCommandLine := flag.CommandLine
+	defer func() {
+		os.Args = oldArgs
+		flag.CommandLine = oldFlagCommandLine
+	}()
+
+	programName := "server"
+
+	tests := []struct {
+		name          string
+		args          []string
+		envVars       map[string]string
+		expected      *ServerConfig
+		expectedError bool
+	}{
+		{
+			name: "command_line_flags",
+			args: []string{programName, "-a", "localhost:9090", "-i", "0", "-f", "/tmp/metrics.json", "-r=false"},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:9090",
+				StoreInterval:    0,
+				FileStoragePath:  "/tmp/metrics.json",
+				Restore:          false,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+			},
+		},
+		{
+			name: "env_vars_override_flags",
+			args: []string{programName, "-i", "0", "-f", "/tmp/metrics.json", "-r=false"},
+			envVars: map[string]string{
+				"STORE_INTERVAL":    "10",
+				"FILE_STORAGE_PATH": "/var/lib/metrics.json",
+				"RESTORE":           "true",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    10,
+				FileStoragePath:  "/var/lib/metrics.json",
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+			},
+		},
+		{
+			name: "database_dsn",
+			args: []string{programName, "-d", "postgres://flag@localhost/metrics"},
+			envVars: map[string]string{
+				"DATABASE_DSN": "postgres://env@localhost/metrics",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				DatabaseDSN:      "postgres://env@localhost/metrics",
+			},
+		},
+		{
+			name: "sqlite_path",
+			args: []string{programName, "-sqlite", "/tmp/flag.db"},
+			envVars: map[string]string{
+				"SQLITE_PATH": "/var/lib/metrics.db",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				SQLitePath:       "/var/lib/metrics.db",
+			},
+		},
+		{
+			name: "key",
+			args: []string{programName, "-k", "flag-key"},
+			envVars: map[string]string{
+				"KEY": "env-key",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				Key:              "env-key",
+			},
+		},
+		{
+			name: "crypto_key",
+			args: []string{programName, "-crypto-key", "/etc/metrics/private.pem"},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				CryptoKey:        "/etc/metrics/private.pem",
+			},
+		},
+		{
+			name: "retry_delays",
+			args: []string{programName, "-retry-delays", "10ms,20ms"},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+			},
+		},
+		{
+			name: "shutdown_timeout",
+			args: []string{programName, "-shutdown-timeout", "5"},
+			envVars: map[string]string{
+				"SHUTDOWN_TIMEOUT": "30",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  30,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+			},
+		},
+		{
+			name: "statsd_address",
+			args: []string{programName, "-statsd", ":8125"},
+			envVars: map[string]string{
+				"STATSD_ADDRESS": "127.0.0.1:9125",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				StatsdAddress:    "127.0.0.1:9125",
+			},
+		},
+		{
+			name: "history",
+			args: []string{programName, "-history-size", "100", "-history-retention", "60"},
+			envVars: map[string]string{
+				"HISTORY_SIZE": "500",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistorySize:      500,
+				HistoryRetention: 60,
+				AlertInterval:    10,
+				LogLevel:         "info",
+			},
+		},
+		{
+			name:          "invalid_history_size",
+			args:          []string{programName},
+			envVars:       map[string]string{"HISTORY_SIZE": "many"},
+			expectedError: true,
+		},
+		{
+			name: "alerting",
+			args: []string{programName, "-alert-rules", "/etc/metrics/alerts.json", "-alert-interval", "5"},
+			envVars: map[string]string{
+				"ALERT_WEBHOOK":  "http://localhost:9000/hook",
+				"ALERT_INTERVAL": "30",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertRules:       "/etc/metrics/alerts.json",
+				AlertWebhook:     "http://localhost:9000/hook",
+				AlertInterval:    30,
+				LogLevel:         "info",
+			},
+		},
+		{
+			name:          "invalid_alert_interval",
+			args:          []string{programName},
+			envVars:       map[string]string{"ALERT_INTERVAL": "often"},
+			expectedError: true,
+		},
+		{
+			name: "grpc_address",
+			args: []string{programName, "-grpc-address", ":3200"},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				GRPCAddress:      ":3200",
+			},
+		},
+		{
+			name: "trusted_subnet",
+			args: []string{programName, "-t", "10.0.0.0/8"},
+			envVars: map[string]string{
+				"TRUSTED_SUBNET": "192.168.1.0/24",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				TrustedSubnet:    "192.168.1.0/24",
+			},
+		},
+		{
+			name:          "invalid_trusted_subnet",
+			args:          []string{programName, "-t", "192.168.1.1"},
+			expectedError: true,
+		},
+		{
+			name: "mutual_tls",
+			args: []string{programName, "-tls-cert", "server.crt", "-tls-key", "server.key"},
+			envVars: map[string]string{
+				"TLS_CLIENT_CA": "ca.pem",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "info",
+				TLSCert:          "server.crt",
+				TLSKey:           "server.key",
+				TLSClientCA:      "ca.pem",
+			},
+		},
+		{
+			name:          "tls_cert_without_key",
+			args:          []string{programName, "-tls-cert", "server.crt"},
+			expectedError: true,
+		},
+		{
+			name:          "client_ca_without_tls",
+			args:          []string{programName, "-tls-client-ca", "ca.pem"},
+			expectedError: true,
+		},
+		{
+			name: "json_logs",
+			args: []string{programName, "-log-level", "warn"},
+			envVars: map[string]string{
+				"LOG_LEVEL": "debug",
+				"LOG_JSON":  "true",
+			},
+			expected: &ServerConfig{
+				MetricServerHost: "localhost:8080",
+				StoreInterval:    300,
+				Restore:          true,
+				RetryDelays:      retry.DefaultDelays,
+				ShutdownTimeout:  10,
+				HistoryRetention: 3600,
+				AlertInterval:    10,
+				LogLevel:         "debug",
+				LogJSON:          true,
+			},
+		},
+		{
+			name: "invalid_log_json",
+			args: []string{programName},
+			envVars: map[string]string{
+				"LOG_JSON": "yes please",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid_shutdown_timeout",
+			args: []string{programName},
+			envVars: map[string]string{
+				"SHUTDOWN_TIMEOUT": "soon",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid_store_interval",
+			args: []string{programName},
+			envVars: map[string]string{
+				"STORE_INTERVAL": "invalid",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalid_restore",
+			args: []string{programName},
+			envVars: map[string]string{
+				"RESTORE": "invalid",
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag.CommandLine = flag.NewFlagSet(programName, flag.ExitOnError)
+
+			for _, k := range []string{"ADDRESS", "STORE_INTERVAL", "FILE_STORAGE_PATH", "RESTORE", "DATABASE_DSN", "SQLITE_PATH", "KEY", "CRYPTO_KEY", "RETRY_DELAYS", "SHUTDOWN_TIMEOUT", "STATSD_ADDRESS", "HISTORY_SIZE", "HISTORY_RETENTION", "ALERT_RULES", "ALERT_WEBHOOK", "ALERT_INTERVAL", "GRPC_ADDRESS", "TRUSTED_SUBNET", "TLS_CERT", "TLS_KEY", "TLS_CLIENT_CA", "LOG_LEVEL", "LOG_JSON"} {
+				os.Unsetenv(k)
+			}
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			os.Args = tt.args
+
+			cfg, err := ParseServerFlags()
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, cfg)
+			}
+		})
+	}
+}
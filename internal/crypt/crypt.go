@@ -0,0 +1,223 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/iudanet/yp-metrics-go/internal/httpbody"
+)
+
+// HeaderName помечает запрос, тело которого зашифровано, значение — схема шифрования
+const (
+	HeaderName = "X-Encrypted"
+	Scheme     = "rsa-oaep-aes256-gcm"
+)
+
+// aesKeySize — размер симметричного ключа AES-256
+const aesKeySize = 32
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Encrypt шифрует data гибридной схемой: случайный ключ AES-256-GCM
+// шифруется RSA-OAEP (SHA-256) открытым ключом получателя.
+// Формат: [длина зашифрованного ключа, 2 байта][зашифрованный ключ][nonce][шифротекст].
+func Encrypt(pub *rsa.PublicKey, data []byte) ([]byte, error) {
+	key := make([]byte, aesKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	encKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 2, 2+len(encKey)+len(nonce)+len(data)+gcm.Overhead())
+	binary.BigEndian.PutUint16(out, uint16(len(encKey)))
+	out = append(out, encKey...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, nil), nil
+}
+
+// Decrypt расшифровывает данные, подготовленные Encrypt
+func Decrypt(priv *rsa.PrivateKey, data []byte) ([]byte, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidCiphertext
+	}
+	keyLen := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < keyLen {
+		return nil, ErrInvalidCiphertext
+	}
+	key, err := rsa.DecryptOAEP(sha256.New(), nil, priv, data[:keyLen], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	data = data[keyLen:]
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+// Middleware расшифровывает тела запросов закрытым ключом.
+// Если ключ задан, непустое тело без заголовка HeaderName отклоняется:
+// агенты с открытым ключом шифруют всё, что передают. Поэтому middleware
+// подключается только к маршрутам обновления метрик, чтение остаётся доступным без шифрования.
+// Тела больше httpbody.MaxSize отклоняются с кодом 413. При nil-ключе обработчик возвращается без изменений.
+func Middleware(priv *rsa.PrivateKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if priv == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, ok := httpbody.Read(w, r)
+			if !ok {
+				return
+			}
+
+			scheme := r.Header.Get(HeaderName)
+			switch {
+			case scheme == Scheme:
+				plain, err := Decrypt(priv, body)
+				if err != nil {
+					http.Error(w, "failed to decrypt body", http.StatusBadRequest)
+					return
+				}
+				body = plain
+				r.Header.Del(HeaderName)
+				r.ContentLength = int64(len(body))
+			case scheme != "":
+				http.Error(w, "unsupported encryption scheme", http.StatusBadRequest)
+				return
+			case len(body) > 0:
+				http.Error(w, "encrypted body required", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GenerateKeyPair создаёт пару ключей RSA в PEM: закрытый в PKCS#1, открытый в PKIX
+func GenerateKeyPair(bits int) (privPEM, pubPEM []byte, err error) {
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	privPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+	pubPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	return privPEM, pubPEM, nil
+}
+
+// LoadPublicKey читает открытый ключ RSA из PEM-файла (PKIX или PKCS#1)
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not RSA")
+		}
+		return pub, nil
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+}
+
+// LoadPrivateKey читает закрытый ключ RSA из PEM-файла (PKCS#1 или PKCS#8)
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		return priv, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not RSA")
+		}
+		return priv, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
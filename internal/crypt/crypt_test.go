@@ -0,0 +1,149 @@
+package crypt
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/httpbody"
+)
+
+// writeKeyPair генерирует пару ключей во временный каталог
+func writeKeyPair(t *testing.T) (privPath, pubPath string) {
+	t.Helper()
+	privPEM, pubPEM, err := GenerateKeyPair(2048)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	privPath = filepath.Join(dir, "private.pem")
+	pubPath = filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(privPath, privPEM, 0o600))
+	require.NoError(t, os.WriteFile(pubPath, pubPEM, 0o644))
+	return privPath, pubPath
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	privPath, pubPath := writeKeyPair(t)
+	priv, err := LoadPrivateKey(privPath)
+	require.NoError(t, err)
+	pub, err := LoadPublicKey(pubPath)
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":1}`), 100)
+
+	encrypted, err := Encrypt(pub, data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "Alloc")
+
+	decrypted, err := Decrypt(priv, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	encrypted[len(encrypted)-1] ^= 0xff
+	_, err = Decrypt(priv, encrypted)
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = Decrypt(priv, []byte{0})
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestLoadKeyErrors(t *testing.T) {
+	privPath, pubPath := writeKeyPair(t)
+
+	_, err := LoadPublicKey(privPath)
+	assert.Error(t, err, "private key must not load as public")
+	_, err = LoadPrivateKey(pubPath)
+	assert.Error(t, err, "public key must not load as private")
+	_, err = LoadPublicKey(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestMiddleware(t *testing.T) {
+	privPath, pubPath := writeKeyPair(t)
+	priv, err := LoadPrivateKey(privPath)
+	require.NoError(t, err)
+	pub, err := LoadPublicKey(pubPath)
+	require.NoError(t, err)
+
+	const body = `[{"id":"test","type":"counter","delta":1}]`
+	encrypted, err := Encrypt(pub, []byte(body))
+	require.NoError(t, err)
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(data)
+	})
+
+	tests := []struct {
+		name       string
+		body       []byte
+		scheme     string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "encrypted_body",
+			body:       encrypted,
+			scheme:     Scheme,
+			wantStatus: http.StatusOK,
+			wantBody:   body,
+		},
+		{
+			name:       "plain_body_rejected",
+			body:       []byte(body),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty_body_allowed",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "broken_ciphertext",
+			body:       []byte(body),
+			scheme:     Scheme,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown_scheme",
+			body:       encrypted,
+			scheme:     "rot13",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(tt.body))
+			if tt.scheme != "" {
+				req.Header.Set(HeaderName, tt.scheme)
+			}
+			w := httptest.NewRecorder()
+
+			Middleware(priv)(echo).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewareBodyLimit(t *testing.T) {
+	privPath, _ := writeKeyPair(t)
+	priv, err := LoadPrivateKey(privPath)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(make([]byte, httpbody.MaxSize+1)))
+	req.Header.Set(HeaderName, Scheme)
+	w := httptest.NewRecorder()
+	Middleware(priv)(http.NotFoundHandler()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -0,0 +1,190 @@
+package history
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// Point — значение метрики в момент времени
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Store хранит историю значений каждого ряда в кольцевом буфере.
+// Для ряда остаётся не больше maxSamples последних значений и не старше maxAge.
+type Store struct {
+	maxSamples int
+	maxAge     time.Duration
+	now        func() time.Time
+
+	mutex     sync.Mutex
+	series    map[string]*ring
+	lastSweep time.Time
+}
+
+// NewStore создаёт хранилище истории. maxAge равный 0 отключает ограничение по возрасту.
+func NewStore(maxSamples int, maxAge time.Duration) *Store {
+	return &Store{
+		maxSamples: maxSamples,
+		maxAge:     maxAge,
+		now:        time.Now,
+		series:     make(map[string]*ring),
+	}
+}
+
+// seriesID различает одноимённые gauge и counter
+func seriesID(mType, key string) string {
+	return mType + "/" + key
+}
+
+// Record добавляет текущее значение ряда key типа mType
+func (s *Store) Record(mType, key string, value float64) {
+	now := s.now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id := seriesID(mType, key)
+	r, ok := s.series[id]
+	if !ok {
+		r = &ring{}
+		s.series[id] = r
+	}
+	r.push(Point{Time: now, Value: value}, s.maxSamples)
+	s.expire(id, r, now)
+	s.sweep(now)
+}
+
+// Query возвращает значения ряда в интервале [from, to].
+// Если step больше нуля, точки объединяются в интервалы длиной step, выровненные
+// как time.Truncate: для gauge берётся среднее, для counter — последнее накопленное значение.
+// Время точки — начало интервала.
+func (s *Store) Query(mType, key string, from, to time.Time, step time.Duration) []Point {
+	now := s.now()
+	s.mutex.Lock()
+	id := seriesID(mType, key)
+	r, ok := s.series[id]
+	var points []Point
+	if ok {
+		s.expire(id, r, now)
+		points = r.between(from, to)
+	}
+	s.mutex.Unlock()
+
+	if step <= 0 || len(points) == 0 {
+		return points
+	}
+	return downsample(points, mType, step)
+}
+
+// expire удаляет значения старше maxAge, а опустевший ряд — целиком
+func (s *Store) expire(id string, r *ring, now time.Time) {
+	if s.maxAge <= 0 {
+		return
+	}
+	r.dropBefore(now.Add(-s.maxAge))
+	if r.size == 0 {
+		delete(s.series, id)
+	}
+}
+
+// sweep не чаще раза в maxAge проверяет все ряды, чтобы удалить те,
+// в которые больше не пишут и которые никто не запрашивает
+func (s *Store) sweep(now time.Time) {
+	if s.maxAge <= 0 || now.Sub(s.lastSweep) < s.maxAge {
+		return
+	}
+	s.lastSweep = now
+	for id, r := range s.series {
+		s.expire(id, r, now)
+	}
+}
+
+func downsample(points []Point, mType string, step time.Duration) []Point {
+	var (
+		result []Point
+		sum    float64
+		count  int
+	)
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		if mType == models.Gauge {
+			result[len(result)-1].Value = sum / float64(count)
+		}
+		sum, count = 0, 0
+	}
+	for _, p := range points {
+		bucket := p.Time.Truncate(step)
+		if len(result) == 0 || !result[len(result)-1].Time.Equal(bucket) {
+			flush()
+			result = append(result, Point{Time: bucket})
+		}
+		// для counter итог интервала — последнее значение в нём
+		result[len(result)-1].Value = p.Value
+		sum += p.Value
+		count++
+	}
+	flush()
+	return result
+}
+
+// ring — кольцевой буфер значений, упорядоченных по времени.
+// Память выделяется по мере роста до maxSamples.
+type ring struct {
+	buf  []Point
+	head int
+	size int
+}
+
+func (r *ring) push(p Point, maxSamples int) {
+	switch {
+	case r.size == len(r.buf) && len(r.buf) < maxSamples:
+		// буфер заполнен, но ещё может расти: разворачиваем его в линейный порядок
+		if r.head != 0 {
+			r.buf = slices.Concat(r.buf[r.head:], r.buf[:r.head])
+			r.head = 0
+		}
+		r.buf = append(r.buf, p)
+		r.size++
+	case r.size == len(r.buf):
+		if len(r.buf) == 0 {
+			return
+		}
+		// вытесняем самое старое значение
+		r.buf[r.head] = p
+		r.head = (r.head + 1) % len(r.buf)
+	default:
+		r.buf[(r.head+r.size)%len(r.buf)] = p
+		r.size++
+	}
+}
+
+func (r *ring) at(i int) Point {
+	return r.buf[(r.head+i)%len(r.buf)]
+}
+
+func (r *ring) dropBefore(cutoff time.Time) {
+	for r.size > 0 && r.buf[r.head].Time.Before(cutoff) {
+		r.head = (r.head + 1) % len(r.buf)
+		r.size--
+	}
+	if r.size == 0 {
+		r.head = 0
+	}
+}
+
+func (r *ring) between(from, to time.Time) []Point {
+	var points []Point
+	for i := 0; i < r.size; i++ {
+		p := r.at(i)
+		if p.Time.Before(from) || p.Time.After(to) {
+			continue
+		}
+		points = append(points, p)
+	}
+	return points
+}
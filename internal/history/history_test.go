@@ -0,0 +1,142 @@
+package history
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+var base = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeClock возвращает время, которое тест сдвигает вручную
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore(maxSamples int, maxAge time.Duration) (*Store, *fakeClock) {
+	clock := &fakeClock{now: base}
+	s := NewStore(maxSamples, maxAge)
+	s.now = clock.Now
+	return s, clock
+}
+
+func values(points []Point) []float64 {
+	result := make([]float64, len(points))
+	for i, p := range points {
+		result[i] = p.Value
+	}
+	return result
+}
+
+func TestStoreRetentionByCount(t *testing.T) {
+	s, clock := newTestStore(3, 0)
+	for i := range 5 {
+		s.Record(models.Gauge, "g", float64(i))
+		clock.now = clock.now.Add(time.Second)
+	}
+	all := s.Query(models.Gauge, "g", time.Time{}, clock.now, 0)
+	assert.Equal(t, []float64{2, 3, 4}, values(all))
+	assert.Equal(t, base.Add(2*time.Second), all[0].Time)
+
+	// одноимённый counter — отдельный ряд
+	assert.Empty(t, s.Query(models.Counter, "g", time.Time{}, clock.now, 0))
+}
+
+func TestStoreRetentionByAge(t *testing.T) {
+	s, clock := newTestStore(100, 10*time.Second)
+	for i := range 5 {
+		s.Record(models.Gauge, "g", float64(i))
+		clock.now = clock.now.Add(5 * time.Second)
+	}
+	// сейчас base+25s: значения старше base+15s удалены
+	assert.Equal(t, []float64{3, 4}, values(s.Query(models.Gauge, "g", time.Time{}, clock.now, 0)))
+
+	// после вытеснения по возрасту буфер продолжает расти до maxSamples
+	for i := 5; i < 8; i++ {
+		s.Record(models.Gauge, "g", float64(i))
+	}
+	assert.Equal(t, []float64{3, 4, 5, 6, 7}, values(s.Query(models.Gauge, "g", time.Time{}, clock.now, 0)))
+
+	clock.now = clock.now.Add(time.Minute)
+	assert.Empty(t, s.Query(models.Gauge, "g", time.Time{}, clock.now, 0))
+}
+
+func TestStoreDeletesExpiredSeries(t *testing.T) {
+	s, clock := newTestStore(100, 10*time.Second)
+	s.Record(models.Gauge, "stale", 1)
+	s.Record(models.Gauge, "queried", 2)
+	require.Len(t, s.series, 2)
+
+	clock.now = clock.now.Add(time.Minute)
+	assert.Empty(t, s.Query(models.Gauge, "queried", time.Time{}, clock.now, 0))
+	assert.NotContains(t, s.series, seriesID(models.Gauge, "queried"))
+
+	// запись в другой ряд удаляет устаревший ряд, который никто не запрашивает
+	s.Record(models.Gauge, "fresh", 3)
+	assert.Equal(t, []string{seriesID(models.Gauge, "fresh")}, slices.Collect(maps.Keys(s.series)))
+}
+
+func TestStoreQueryRange(t *testing.T) {
+	s, clock := newTestStore(100, 0)
+	for i := range 6 {
+		s.Record(models.Gauge, "g", float64(i))
+		clock.now = clock.now.Add(10 * time.Second)
+	}
+	points := s.Query(models.Gauge, "g", base.Add(10*time.Second), base.Add(30*time.Second), 0)
+	assert.Equal(t, []float64{1, 2, 3}, values(points))
+}
+
+func TestStoreDownsample(t *testing.T) {
+	s, clock := newTestStore(100, 0)
+	for i := range 6 {
+		s.Record(models.Gauge, "g", float64(i))
+		s.Record(models.Counter, "c", float64(i*10))
+		clock.now = clock.now.Add(20 * time.Second)
+	}
+
+	gauges := s.Query(models.Gauge, "g", time.Time{}, clock.now, time.Minute)
+	assert.Equal(t, []Point{
+		{Time: base, Value: 1},
+		{Time: base.Add(time.Minute), Value: 4},
+	}, gauges)
+
+	counters := s.Query(models.Counter, "c", time.Time{}, clock.now, time.Minute)
+	assert.Equal(t, []float64{20, 50}, values(counters))
+}
+
+func TestWrap(t *testing.T) {
+	h, _ := newTestStore(10, 0)
+	repo := Wrap(storage.NewStorage(), h)
+
+	require.NoError(t, repo.SetGauge("g", 1.5))
+	require.NoError(t, repo.SetCounter("c", 2))
+	require.NoError(t, repo.IncrCounter("c"))
+	require.NoError(t, repo.UpdateBatch([]models.Metrics{
+		{ID: "c", MType: models.Counter, Delta: ptr(int64(1))},
+		{ID: "c", MType: models.Counter, Delta: ptr(int64(1))},
+		{ID: "g", MType: models.Gauge, Value: ptr(2.5)},
+	}))
+
+	to := base.Add(time.Hour)
+	assert.Equal(t, []float64{1.5, 2.5}, values(h.Query(models.Gauge, "g", time.Time{}, to, 0)))
+	assert.Equal(t, []float64{2, 3, 5}, values(h.Query(models.Counter, "c", time.Time{}, to, 0)))
+
+	// ошибка хранилища не попадает в историю
+	require.Error(t, repo.UpdateBatch([]models.Metrics{{ID: "g", MType: models.Gauge}}))
+	assert.Len(t, h.Query(models.Gauge, "g", time.Time{}, to, 0), 2)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
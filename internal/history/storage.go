@@ -0,0 +1,88 @@
+package history
+
+import (
+	"log/slog"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+// recordingStorage дополняет хранилище записью истории: после каждого успешного
+// изменения текущее значение ряда читается из хранилища и добавляется в Store.
+// Для счётчиков сохраняется накопленное значение, а не приращение.
+type recordingStorage struct {
+	storage.Repository
+	history *Store
+}
+
+// Wrap возвращает хранилище, которое записывает историю изменений repo в history
+func Wrap(repo storage.Repository, history *Store) storage.Repository {
+	return &recordingStorage{Repository: repo, history: history}
+}
+
+func (s *recordingStorage) SetGauge(name string, value float64) error {
+	if err := s.Repository.SetGauge(name, value); err != nil {
+		return err
+	}
+	s.recordGauge(name)
+	return nil
+}
+
+func (s *recordingStorage) SetCounter(name string, value int64) error {
+	if err := s.Repository.SetCounter(name, value); err != nil {
+		return err
+	}
+	s.recordCounter(name)
+	return nil
+}
+
+func (s *recordingStorage) IncrCounter(name string) error {
+	if err := s.Repository.IncrCounter(name); err != nil {
+		return err
+	}
+	s.recordCounter(name)
+	return nil
+}
+
+func (s *recordingStorage) UpdateBatch(metrics []models.Metrics) error {
+	if err := s.Repository.UpdateBatch(metrics); err != nil {
+		return err
+	}
+	// одна метрика может встречаться в пакете несколько раз, записываем итог один раз
+	seen := make(map[string]bool, len(metrics))
+	for _, m := range metrics {
+		id := seriesID(m.MType, m.ID)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		switch m.MType {
+		case models.Gauge:
+			s.recordGauge(m.ID)
+		case models.Counter:
+			s.recordCounter(m.ID)
+		}
+	}
+	return nil
+}
+
+// recordGauge добавляет в историю текущее значение gauge. Значение уже записано
+// в хранилище, поэтому ошибка чтения только оставляет пропуск в истории.
+func (s *recordingStorage) recordGauge(name string) {
+	value, err := s.Repository.GetGauge(name)
+	if err != nil {
+		slog.Warn("history: failed to read gauge", "name", name, "error", err)
+		return
+	}
+	s.history.Record(models.Gauge, name, value)
+}
+
+// recordCounter добавляет в историю накопленное значение счётчика
+func (s *recordingStorage) recordCounter(name string) {
+	value, err := s.Repository.GetCounter(name)
+	if err != nil {
+		slog.Warn("history: failed to read counter", "name", name, "error", err)
+		return
+	}
+	s.history.Record(models.Counter, name, float64(value))
+}
@@ -0,0 +1,27 @@
+package httpbody
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// MaxSize ограничивает тело запроса, которое middleware читает в память целиком
+const MaxSize = 10 << 20
+
+// Read читает и закрывает тело запроса, не больше MaxSize байт.
+// При ошибке отвечает клиенту 413 для слишком большого тела или 400 и возвращает false.
+func Read(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxSize))
+	r.Body.Close()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
@@ -0,0 +1,49 @@
+package httpbody
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantOK     bool
+		wantStatus int
+	}{
+		{
+			name:       "within_limit",
+			req:        httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, MaxSize))),
+			wantOK:     true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "too_large",
+			req:        httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, MaxSize+1))),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "read_error",
+			req:        httptest.NewRequest(http.MethodPost, "/", iotest.ErrReader(errors.New("broken"))),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			body, ok := Read(w, tt.req)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if ok {
+				assert.Len(t, body, MaxSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// New создаёт структурированный логгер с минимальным уровнем level (debug, info, warn, error).
+// При jsonFormat записи пишутся в JSON, иначе в текстовом формате key=value.
+func New(w io.Writer, level string, jsonFormat bool) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	if jsonFormat {
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), nil
+}
+
+// Middleware пишет в лог метод, URI, статус, размер ответа и длительность каждого запроса
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			lw := &loggingWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(lw, r)
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+				slog.String("method", r.Method),
+				slog.String("uri", r.RequestURI),
+				slog.Int("status", lw.status),
+				slog.Int("size", lw.size),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// loggingWriter запоминает статус и число записанных байт ответа
+type loggingWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (l *loggingWriter) WriteHeader(statusCode int) {
+	if !l.wroteHeader {
+		l.status = statusCode
+		l.wroteHeader = true
+	}
+	l.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (l *loggingWriter) Write(b []byte) (int, error) {
+	l.wroteHeader = true
+	n, err := l.ResponseWriter.Write(b)
+	l.size += n
+	return n, err
+}
+
+// Unwrap даёт http.ResponseController доступ к исходному ResponseWriter
+func (l *loggingWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
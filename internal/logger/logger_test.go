@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     string
+		json      bool
+		wantErr   bool
+		wantDebug bool
+		wantText  string
+	}{
+		{name: "json_info", level: "info", json: true, wantText: `"msg":"visible"`},
+		{name: "text_debug", level: "DEBUG", wantDebug: true, wantText: "msg=visible"},
+		{name: "warn_hides_info", level: "warn"},
+		{name: "invalid_level", level: "verbose", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := New(&buf, tt.level, tt.json)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			l.Debug("hidden-debug")
+			l.Info("visible")
+			assert.Equal(t, tt.wantDebug, bytes.Contains(buf.Bytes(), []byte("hidden-debug")))
+			if tt.wantText == "" {
+				assert.Empty(t, buf.String())
+			} else {
+				assert.Contains(t, buf.String(), tt.wantText)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus float64
+		wantSize   float64
+	}{
+		{
+			name: "explicit_status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "not found", http.StatusNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantSize:   float64(len("not found\n")),
+		},
+		{
+			name: "implicit_ok",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("hello"))
+			},
+			wantStatus: http.StatusOK,
+			wantSize:   5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := New(&buf, "info", true)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/value/gauge/Alloc?host=a", nil)
+			w := httptest.NewRecorder()
+			Middleware(l)(tt.handler).ServeHTTP(w, r)
+
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+			assert.Equal(t, "request", entry["msg"])
+			assert.Equal(t, http.MethodGet, entry["method"])
+			assert.Equal(t, "/value/gauge/Alloc?host=a", entry["uri"])
+			assert.Equal(t, tt.wantStatus, entry["status"])
+			assert.Equal(t, tt.wantSize, entry["size"])
+			assert.Contains(t, entry, "duration")
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidSeriesKey = errors.New("invalid series key")
+
+// Key возвращает идентификатор ряда: имя вместе с метками
+func (m Metrics) Key() string {
+	return SeriesKey(m.ID, m.Labels)
+}
+
+// SeriesKey кодирует имя и метки в строку вида name{a="1",b="2"}, под которой ряд хранится
+// во всех хранилищах. Метки сортируются по имени, так что ключ не зависит от порядка.
+// Без меток ключ совпадает с именем, поэтому метрики без меток хранятся как раньше.
+func SeriesKey(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, k := range slices.Sorted(maps.Keys(labels)) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteString(`="`)
+		b.WriteString(EscapeLabelValue(labels[k]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// ParseSeriesKey разбирает ключ, построенный SeriesKey
+func ParseSeriesKey(key string) (string, map[string]string, error) {
+	name, rest, ok := strings.Cut(key, "{")
+	if !ok {
+		return key, nil, nil
+	}
+	if !strings.HasSuffix(rest, "}") {
+		return "", nil, fmt.Errorf("%w %q", ErrInvalidSeriesKey, key)
+	}
+	rest = rest[:len(rest)-1]
+	labels := make(map[string]string)
+	for rest != "" {
+		k, v, ok := strings.Cut(rest, `="`)
+		if !ok || !ValidLabelName(k) {
+			return "", nil, fmt.Errorf("%w %q", ErrInvalidSeriesKey, key)
+		}
+		value, tail, err := unescapeLabelValue(v)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w %q", ErrInvalidSeriesKey, key)
+		}
+		labels[k] = value
+		rest, ok = strings.CutPrefix(tail, ",")
+		if !ok && tail != "" {
+			return "", nil, fmt.Errorf("%w %q", ErrInvalidSeriesKey, key)
+		}
+	}
+	return name, labels, nil
+}
+
+// ValidLabelName проверяет имя метки на соответствие [a-zA-Z_][a-zA-Z0-9_]*
+func ValidLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// EscapeLabelValue экранирует обратную косую черту, кавычку и перевод строки
+func EscapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// unescapeLabelValue читает экранированное значение до закрывающей кавычки
+// и возвращает остаток строки после неё
+func unescapeLabelValue(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", "", ErrInvalidSeriesKey
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case '\\', '"':
+				b.WriteByte(s[i])
+			default:
+				return "", "", ErrInvalidSeriesKey
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", ErrInvalidSeriesKey
+}
+
+// MatchLabels проверяет, что labels содержит все пары из filter
+func MatchLabels(labels, filter map[string]string) bool {
+	for k, v := range filter {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
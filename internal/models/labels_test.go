@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		labels map[string]string
+		key    string
+	}{
+		{name: "no_labels", id: "Alloc", key: "Alloc"},
+		{name: "sorted", id: "Alloc", labels: map[string]string{"host": "web1", "env": "prod"}, key: `Alloc{env="prod",host="web1"}`},
+		{name: "escaped", id: "x", labels: map[string]string{"path": "C:\\tmp \"a\"\nb"}, key: `x{path="C:\\tmp \"a\"\nb"}`},
+		{name: "empty_value", id: "x", labels: map[string]string{"a": ""}, key: `x{a=""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := Metrics{ID: tt.id, Labels: tt.labels}.Key()
+			assert.Equal(t, tt.key, key)
+
+			id, labels, err := ParseSeriesKey(key)
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, id)
+			if len(tt.labels) == 0 {
+				assert.Empty(t, labels)
+			} else {
+				assert.Equal(t, tt.labels, labels)
+			}
+		})
+	}
+}
+
+func TestParseSeriesKeyInvalid(t *testing.T) {
+	for _, key := range []string{
+		`x{a="1"`,
+		`x{a=1}`,
+		`x{a="1"b="2"}`,
+		`x{1a="1"}`,
+		`x{a="\t"}`,
+		`x{a="1}`,
+	} {
+		_, _, err := ParseSeriesKey(key)
+		assert.ErrorIs(t, err, ErrInvalidSeriesKey, key)
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"host": "web1", "env": "prod"}
+	assert.True(t, MatchLabels(labels, nil))
+	assert.True(t, MatchLabels(labels, map[string]string{"host": "web1"}))
+	assert.False(t, MatchLabels(labels, map[string]string{"host": "web2"}))
+	assert.False(t, MatchLabels(labels, map[string]string{"dc": "eu"}))
+}
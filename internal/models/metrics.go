@@ -0,0 +1,17 @@
+package models
+
+// Типы метрик, поддерживаемые сервером
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+// Metrics описывает метрику в JSON API
+type Metrics struct {
+	ID    string   `json:"id"`              // имя метрики
+	MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
+	Delta *int64   `json:"delta,omitempty"` // значение метрики в случае передачи counter
+	Value *float64 `json:"value,omitempty"` // значение метрики в случае передачи gauge
+	// Labels — необязательные метки ряда; вместе с ID определяют ряд, см. SeriesKey
+	Labels map[string]string `json:"labels,omitempty"`
+}
@@ -0,0 +1,36 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultDelays — паузы между повторными попытками по умолчанию
+var DefaultDelays = []time.Duration{time.Second, 3 * time.Second, 5 * time.Second}
+
+// Policy описывает, какие ошибки повторять и с какими паузами.
+// Всего выполняется len(Delays)+1 попыток.
+type Policy struct {
+	Delays    []time.Duration
+	Retriable func(error) bool
+}
+
+// Do вызывает fn, пока она возвращает повторяемую ошибку и не исчерпаны паузы.
+// Возвращает последнюю ошибку fn или ошибку контекста, если он отменён во время паузы.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	err := fn()
+	for _, delay := range p.Delays {
+		if err == nil || p.Retriable == nil || !p.Retriable(err) {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		err = fn()
+	}
+	return err
+}
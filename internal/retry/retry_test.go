@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errPermanent = errors.New("permanent")
+)
+
+func TestPolicyDo(t *testing.T) {
+	policy := Policy{
+		Delays: []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond},
+		Retriable: func(err error) bool {
+			return errors.Is(err, errTransient)
+		},
+	}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "success_first_try",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "success_after_retries",
+			errs:      []error{errTransient, errTransient, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "permanent_error_not_retried",
+			errs:      []error{errPermanent},
+			wantErr:   errPermanent,
+			wantCalls: 1,
+		},
+		{
+			name:      "attempts_exhausted",
+			errs:      []error{errTransient, errTransient, errTransient, errTransient, nil},
+			wantErr:   errTransient,
+			wantCalls: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := policy.Do(context.Background(), func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestPolicyDoCanceled(t *testing.T) {
+	policy := Policy{
+		Delays:    []time.Duration{time.Hour},
+		Retriable: func(error) bool { return true },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := policy.Do(ctx, func() error {
+		calls++
+		return errTransient
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
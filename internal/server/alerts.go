@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/iudanet/yp-metrics-go/internal/alert"
+)
+
+// WithAlerts включает выдачу активных алертов на /alerts
+func WithAlerts(e *alert.Engine) Option {
+	return func(s *service) {
+		s.alerts = e
+	}
+}
+
+// GetAlerts возвращает pending и firing алерты в формате JSON
+func (s *service) GetAlerts(w http.ResponseWriter, req *http.Request) {
+	if s.alerts == nil {
+		http.Error(w, "alerting is disabled", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, s.alerts.Active())
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/alert"
+	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+func TestGetAlerts(t *testing.T) {
+	repo := storage.NewStorage()
+	require.NoError(t, repo.SetGauge("HeapAlloc", 200))
+	engine := alert.NewEngine(repo, []alert.Rule{
+		{Name: "HighHeap", Type: "gauge", Metric: "HeapAlloc", Op: ">", Threshold: 100},
+		{Name: "Idle", Type: "gauge", Metric: "HeapAlloc", Op: "<", Threshold: 100},
+	})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	engine.Evaluate(context.Background(), now)
+
+	svc := NewService(repo, config.NewServerConfig(), WithAlerts(engine))
+	w := httptest.NewRecorder()
+	svc.GetAlerts(w, httptest.NewRequest(http.MethodGet, "/alerts", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{
+		"rule": "HighHeap",
+		"metric": "HeapAlloc",
+		"state": "firing",
+		"value": 200,
+		"active_at": "2024-01-01T12:00:00Z",
+		"fired_at": "2024-01-01T12:00:00Z"
+	}]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	NewService(repo, config.NewServerConfig()).GetAlerts(w, httptest.NewRequest(http.MethodGet, "/alerts", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
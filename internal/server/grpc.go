@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/pb"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+// grpcService реализует pb.MetricsServer поверх того же хранилища и проверок, что и HTTP API
+type grpcService struct {
+	pb.UnimplementedMetricsServer
+	svc *service
+}
+
+// NewGRPCService возвращает gRPC-обработчик, разделяющий хранилище с HTTP-сервисом svc
+func NewGRPCService(svc *service) pb.MetricsServer {
+	return &grpcService{svc: svc}
+}
+
+func (g *grpcService) UpdateMetrics(_ context.Context, req *pb.UpdateMetricsRequest) (*pb.UpdateMetricsResponse, error) {
+	if len(req.GetMetrics()) == 0 {
+		return nil, grpcError(errEmptyBatch)
+	}
+	metrics := make([]models.Metrics, len(req.GetMetrics()))
+	series := make([]models.Metrics, len(metrics))
+	for i, m := range req.GetMetrics() {
+		metrics[i] = fromProto(m)
+		if err := validateMetric(&metrics[i], true); err != nil {
+			return nil, grpcError(fmt.Errorf("metric %d: %w", i, err))
+		}
+		series[i] = models.Metrics{ID: metrics[i].Key(), MType: m.GetType(), Delta: metrics[i].Delta, Value: metrics[i].Value}
+	}
+	if err := g.svc.batch.UpdateBatch(series); err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &pb.UpdateMetricsResponse{Metrics: make([]*pb.Metric, len(metrics))}
+	for i := range metrics {
+		g.svc.refreshMetric(&metrics[i])
+		resp.Metrics[i] = toProto(metrics[i])
+	}
+	return resp, nil
+}
+
+func (g *grpcService) GetMetric(_ context.Context, req *pb.GetMetricRequest) (*pb.GetMetricResponse, error) {
+	m := models.Metrics{ID: req.GetId(), MType: req.GetType(), Labels: req.GetLabels()}
+	if err := g.svc.findMetric(&m); err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.GetMetricResponse{Metric: toProto(m)}, nil
+}
+
+func (g *grpcService) ListMetrics(context.Context, *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	counters, err := g.svc.viewer.GetMapCounter()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	gauges, err := g.svc.viewer.GetMapGauge()
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &pb.ListMetricsResponse{Metrics: make([]*pb.Metric, 0, len(counters)+len(gauges))}
+	for _, key := range slices.Sorted(maps.Keys(counters)) {
+		id, labels, err := models.ParseSeriesKey(key)
+		if err != nil {
+			continue
+		}
+		resp.Metrics = append(resp.Metrics, &pb.Metric{Id: id, Type: models.Counter, Delta: counters[key], Labels: labels})
+	}
+	for _, key := range slices.Sorted(maps.Keys(gauges)) {
+		id, labels, err := models.ParseSeriesKey(key)
+		if err != nil {
+			continue
+		}
+		resp.Metrics = append(resp.Metrics, &pb.Metric{Id: id, Type: models.Gauge, Value: gauges[key], Labels: labels})
+	}
+	return resp, nil
+}
+
+// fromProto переводит метрику gRPC в модель; значение берётся из поля, соответствующего типу
+func fromProto(m *pb.Metric) models.Metrics {
+	result := models.Metrics{ID: m.GetId(), MType: m.GetType(), Labels: m.GetLabels()}
+	switch m.GetType() {
+	case models.Gauge:
+		value := m.GetValue()
+		result.Value = &value
+	case models.Counter:
+		delta := m.GetDelta()
+		result.Delta = &delta
+	}
+	return result
+}
+
+func toProto(m models.Metrics) *pb.Metric {
+	result := &pb.Metric{Id: m.ID, Type: m.MType, Labels: m.Labels}
+	if m.Value != nil {
+		result.Value = *m.Value
+	}
+	if m.Delta != nil {
+		result.Delta = *m.Delta
+	}
+	return result
+}
+
+// grpcError переводит ошибку сервиса в статус gRPC по тем же правилам, что statusFromError для HTTP
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, errEmptyName):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, errAmbiguous):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if statusFromError(err) < 500 {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// LoggingInterceptor пишет в лог метод, код ответа и длительность каждого вызова
+func LoggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	slog.LogAttrs(ctx, slog.LevelInfo, "grpc request",
+		slog.String("method", info.FullMethod),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("duration", time.Since(start)),
+	)
+	return resp, err
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/pb"
+	"github.com/iudanet/yp-metrics-go/internal/sign"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+// newGRPCClient поднимает gRPC-сервер в памяти и возвращает клиента с ключом подписи clientKey
+func newGRPCClient(t *testing.T, repo storage.Repository, serverKey, clientKey string) pb.MetricsClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(LoggingInterceptor, sign.UnaryServerInterceptor(serverKey)))
+	pb.RegisterMetricsServer(srv, NewGRPCService(NewService(repo, config.NewServerConfig())))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(sign.UnaryClientInterceptor(clientKey)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMetricsClient(conn)
+}
+
+func TestGRPCService(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewStorage()
+	client := newGRPCClient(t, repo, "secret", "secret")
+
+	resp, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{
+		{Id: "PollCount", Type: "counter", Delta: 2},
+		{Id: "PollCount", Type: "counter", Delta: 3},
+		{Id: "Alloc", Type: "gauge", Value: 1.5, Labels: map[string]string{"host": "a"}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.GetMetrics(), 3)
+	assert.Equal(t, int64(5), resp.GetMetrics()[1].GetDelta())
+	assert.Equal(t, 1.5, resp.GetMetrics()[2].GetValue())
+
+	counter, err := repo.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), counter)
+
+	got, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "Alloc", Type: "gauge", Labels: map[string]string{"host": "a"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, got.GetMetric().GetValue())
+	assert.Equal(t, map[string]string{"host": "a"}, got.GetMetric().GetLabels())
+
+	list, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetMetrics(), 2)
+	assert.Equal(t, "PollCount", list.GetMetrics()[0].GetId())
+	assert.Equal(t, "Alloc", list.GetMetrics()[1].GetId())
+}
+
+func TestGRPCErrors(t *testing.T) {
+	ctx := context.Background()
+	client := newGRPCClient(t, storage.NewStorage(), "", "")
+
+	tests := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{
+			name: "empty_batch",
+			call: func() error {
+				_, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "unknown_type",
+			call: func() error {
+				_, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "x", Type: "histogram"}}})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "not_found",
+			call: func() error {
+				_, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "missing", Type: "gauge"})
+				return err
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, status.Code(tt.call()))
+		})
+	}
+}
+
+func TestGRPCSignature(t *testing.T) {
+	ctx := context.Background()
+	req := &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "Alloc", Type: "gauge", Value: 1}}}
+
+	_, err := newGRPCClient(t, storage.NewStorage(), "secret", "").UpdateMetrics(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = newGRPCClient(t, storage.NewStorage(), "secret", "other").UpdateMetrics(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
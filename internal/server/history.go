@@ -0,0 +1,98 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/history"
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+var errInvalidRange = errors.New("invalid history range")
+
+// historyResponse — ответ /history/: ряд со всеми метками и его точки
+type historyResponse struct {
+	models.Metrics
+	Points []history.Point `json:"points"`
+}
+
+// GetHistory возвращает значения ряда за период:
+// GET /history/{type}/{name}?from=&to=&step=&<метка>=<значение>.
+// from и to принимают RFC 3339 или unix-время в секундах, по умолчанию — вся история до текущего момента.
+// step — длительность вида 30s или число секунд; без него точки возвращаются как есть.
+func (s *service) GetHistory(w http.ResponseWriter, req *http.Request) {
+	if s.history == nil {
+		http.Error(w, "history is disabled", http.StatusNotFound)
+		return
+	}
+	query := req.URL.Query()
+	from, err := parseTime(query.Get("from"), time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTime(query.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	step, err := parseStep(query.Get("step"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.Before(from) {
+		http.Error(w, fmt.Sprintf("%v: to is before from", errInvalidRange), http.StatusBadRequest)
+		return
+	}
+
+	m := models.Metrics{
+		ID:     req.PathValue("name"),
+		MType:  req.PathValue("typeMetrics"),
+		Labels: queryLabels(req, "from", "to", "step"),
+	}
+	if err := s.findMetric(&m); err != nil {
+		http.Error(w, err.Error(), statusFromError(err))
+		return
+	}
+	m.Value, m.Delta = nil, nil
+
+	points := s.history.Query(m.MType, m.Key(), from, to, step)
+	if points == nil {
+		points = []history.Point{}
+	}
+	writeJSON(w, historyResponse{Metrics: m, Points: points})
+}
+
+// parseTime разбирает момент времени в RFC 3339 или unix-секундах
+func parseTime(raw string, def time.Time) (time.Time, error) {
+	if raw == "" {
+		return def, nil
+	}
+	if sec, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: time %q", errInvalidRange, raw)
+	}
+	return t, nil
+}
+
+// parseStep разбирает шаг прореживания: длительность или число секунд
+func parseStep(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	if sec, err := strconv.ParseInt(raw, 10, 64); err == nil && sec >= 0 {
+		return time.Duration(sec) * time.Second, nil
+	}
+	step, err := time.ParseDuration(raw)
+	if err != nil || step < 0 {
+		return 0, fmt.Errorf("%w: step %q", errInvalidRange, raw)
+	}
+	return step, nil
+}
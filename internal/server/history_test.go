@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/history"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+func TestGetHistory(t *testing.T) {
+	store := history.NewStore(100, time.Hour)
+	repo := history.Wrap(storage.NewStorage(), store)
+	require.NoError(t, repo.SetGauge("Alloc", 1))
+	require.NoError(t, repo.SetGauge("Alloc", 3))
+	require.NoError(t, repo.SetGauge(`Load{host="web1"}`, 7))
+	require.NoError(t, repo.SetGauge(`Load{host="web2"}`, 8))
+
+	svc := NewService(repo, config.NewServerConfig(), WithHistory(store))
+	mux := http.NewServeMux()
+	mux.HandleFunc(`GET /history/{typeMetrics}/{name}`, svc.GetHistory)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantLabels map[string]string
+		wantValues []float64
+	}{
+		{name: "raw", target: "/history/gauge/Alloc", wantStatus: http.StatusOK, wantValues: []float64{1, 3}},
+		{name: "downsampled", target: "/history/gauge/Alloc?step=876000h", wantStatus: http.StatusOK, wantValues: []float64{2}},
+		{name: "future_range", target: "/history/gauge/Alloc?from=2999-01-01T00:00:00Z&to=3000-01-01T00:00:00Z", wantStatus: http.StatusOK, wantValues: []float64{}},
+		{
+			name:       "label_filter",
+			target:     "/history/gauge/Load?host=web2&step=60",
+			wantStatus: http.StatusOK,
+			wantLabels: map[string]string{"host": "web2"},
+			wantValues: []float64{8},
+		},
+		{
+			name:       "without_filter_first_series",
+			target:     "/history/gauge/Load?step=60",
+			wantStatus: http.StatusOK,
+			wantLabels: map[string]string{"host": "web1"},
+			wantValues: []float64{7},
+		},
+		{name: "not_found", target: "/history/counter/Alloc", wantStatus: http.StatusNotFound},
+		{name: "invalid_step", target: "/history/gauge/Alloc?step=soon", wantStatus: http.StatusBadRequest},
+		{name: "invalid_from", target: "/history/gauge/Alloc?from=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "reversed_range", target: "/history/gauge/Alloc?from=2000&to=1000", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp historyResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantLabels, resp.Labels)
+			values := make([]float64, len(resp.Points))
+			for i, p := range resp.Points {
+				values[i] = p.Value
+			}
+			assert.Equal(t, tt.wantValues, values)
+		})
+	}
+}
+
+func TestGetHistoryDisabled(t *testing.T) {
+	svc := NewService(storage.NewStorage(), config.NewServerConfig())
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/history/gauge/Alloc", nil)
+	req.SetPathValue("typeMetrics", "gauge")
+	req.SetPathValue("name", "Alloc")
+	svc.GetHistory(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
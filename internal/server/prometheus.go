@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// prometheusContentType — тип текстового формата экспозиции Prometheus 0.0.4
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// GetPrometheus отдаёт все метрики в текстовом формате экспозиции Prometheus
+func (s *service) GetPrometheus(w http.ResponseWriter, r *http.Request) {
+	counters, err := s.viewer.GetMapCounter()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	gauges, err := s.viewer.GetMapGauge()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", prometheusContentType)
+	if err := writePrometheus(w, counters, gauges); err != nil {
+		slog.Error("failed to write prometheus metrics", "error", err)
+	}
+}
+
+// promFamily — метрики с одним именем и типом, отличающиеся метками
+type promFamily struct {
+	id      string
+	mType   string
+	samples []string
+}
+
+// writePrometheus пишет семейства метрик: сначала счётчики, затем gauge, по алфавиту.
+// Ряды с метками одного имени объединяются в семейство с общими HELP и TYPE.
+// Если после приведения имён к допустимому виду два семейства совпадают,
+// выводится только первое — иначе Prometheus отвергнет весь ответ.
+func writePrometheus(w io.Writer, counters map[string]int64, gauges map[string]float64) error {
+	families := make(map[string]*promFamily)
+	var order []string
+	add := func(key, mType, value string) {
+		id, labels, err := models.ParseSeriesKey(key)
+		if err != nil {
+			slog.Warn("prometheus: series skipped", "error", err)
+			return
+		}
+		name := sanitizePrometheusName(id)
+		f, ok := families[name]
+		if !ok {
+			f = &promFamily{id: id, mType: mType}
+			families[name] = f
+			order = append(order, name)
+		}
+		if f.id != id || f.mType != mType {
+			slog.Warn("prometheus: name collision, series skipped", "type", mType, "id", id, "existing_type", f.mType, "existing_id", f.id, "name", name)
+			return
+		}
+		// SeriesKey с пустым именем даёт метки в синтаксисе Prometheus: {a="1",b="2"}
+		f.samples = append(f.samples, name+models.SeriesKey("", labels)+" "+value)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(counters)) {
+		add(key, models.Counter, strconv.FormatInt(counters[key], 10))
+	}
+	for _, key := range slices.Sorted(maps.Keys(gauges)) {
+		add(key, models.Gauge, formatPrometheusFloat(gauges[key]))
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, name := range order {
+		f := families[name]
+		bw.WriteString("# HELP " + name + " " + escapePrometheusHelp(f.id) + "\n")
+		bw.WriteString("# TYPE " + name + " " + f.mType + "\n")
+		for _, sample := range f.samples {
+			bw.WriteString(sample + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// sanitizePrometheusName приводит имя к виду [a-zA-Z_:][a-zA-Z0-9_:]*,
+// заменяя недопустимые символы на подчёркивание
+func sanitizePrometheusName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	var b strings.Builder
+	for i, r := range name {
+		valid := r == '_' || r == ':' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9'
+		if !valid {
+			if i == 0 && r >= '0' && r <= '9' {
+				// имя не может начинаться с цифры
+				b.WriteByte('_')
+				b.WriteRune(r)
+				continue
+			}
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapePrometheusHelp экранирует обратную косую черту и перевод строки в тексте HELP
+func escapePrometheusHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// formatPrometheusFloat записывает число так, как его понимает парсер Prometheus
+func formatPrometheusFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+func TestSanitizePrometheusName(t *testing.T) {
+	tests := map[string]string{
+		"Alloc":          "Alloc",
+		"cpu.load-1m":    "cpu_load_1m",
+		"1xx_responses":  "_1xx_responses",
+		"ns:metric_name": "ns:metric_name",
+		"диск":           "____",
+		"":               "_",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, sanitizePrometheusName(in), in)
+	}
+}
+
+func TestFormatPrometheusFloat(t *testing.T) {
+	assert.Equal(t, "1.5", formatPrometheusFloat(1.5))
+	assert.Equal(t, "1e+21", formatPrometheusFloat(1e21))
+	assert.Equal(t, "NaN", formatPrometheusFloat(math.NaN()))
+	assert.Equal(t, "+Inf", formatPrometheusFloat(math.Inf(1)))
+	assert.Equal(t, "-Inf", formatPrometheusFloat(math.Inf(-1)))
+}
+
+func TestGetPrometheus(t *testing.T) {
+	repo := storage.NewStorage()
+	require.NoError(t, repo.SetCounter("PollCount", 5))
+	require.NoError(t, repo.SetCounter("http.requests", 7))
+	require.NoError(t, repo.SetGauge("Alloc", 1024.5))
+	require.NoError(t, repo.SetGauge("back\\slash", 1))
+	require.NoError(t, repo.SetGauge(`cpu{core="0",host="a\"b"}`, 10))
+	require.NoError(t, repo.SetGauge(`cpu{core="1",host="a\"b"}`, 20))
+	// совпадает с http.requests после приведения имени и пропускается
+	require.NoError(t, repo.SetGauge("http_requests", 3))
+
+	svc := NewService(repo, &config.ServerConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	svc.GetPrometheus(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, prometheusContentType, w.Header().Get("Content-Type"))
+	expected := `# HELP PollCount PollCount
+# TYPE PollCount counter
+PollCount 5
+# HELP http_requests http.requests
+# TYPE http_requests counter
+http_requests 7
+# HELP Alloc Alloc
+# TYPE Alloc gauge
+Alloc 1024.5
+# HELP back_slash back\\slash
+# TYPE back_slash gauge
+back_slash 1
+# HELP cpu cpu
+# TYPE cpu gauge
+cpu{core="0",host="a\"b"} 10
+cpu{core="1",host="a\"b"} 20
+`
+	assert.Equal(t, expected, w.Body.String())
+}
@@ -1,93 +1,363 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"text/template"
 
+	"github.com/iudanet/yp-metrics-go/internal/alert"
 	"github.com/iudanet/yp-metrics-go/internal/config"
+	"github.com/iudanet/yp-metrics-go/internal/history"
+	"github.com/iudanet/yp-metrics-go/internal/models"
 	"github.com/iudanet/yp-metrics-go/internal/storage"
 )
 
-func NewService(storage storage.Repository, cfg *config.ServerConfig) *service {
-	return &service{
+// Option задаёт необязательные параметры сервиса
+type Option func(*service)
+
+// WithHistory включает выдачу истории значений на /history/
+func WithHistory(h *history.Store) Option {
+	return func(s *service) {
+		s.history = h
+	}
+}
+
+func NewService(storage storage.Repository, cfg *config.ServerConfig, opts ...Option) *service {
+	s := &service{
 		storage: storage,
+		batch:   storage,
 		viewer:  storage,
 		config:  cfg,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 type service struct {
 	storage storage.MetricWriter
+	batch   storage.MetricBatchWriter
 	viewer  storage.MetricReader
 	config  *config.ServerConfig
+	history *history.Store
+	alerts  *alert.Engine
 }
 type IndexData struct {
 	Counters map[string]int64
 	Gauges   map[string]float64
 }
 
-func (s *service) UpdateMetric(w http.ResponseWriter, req *http.Request) {
-	typeMetrics := req.PathValue("typeMetrics")
-	name := req.PathValue("name")
-	rawValue := req.PathValue("value")
-	log.Printf("Received metric: type=%s name=%s value=%s", typeMetrics, name, rawValue)
+var (
+	errInvalidType  = errors.New("invalid metric type")
+	errInvalidGauge = errors.New("invalid gauge value")
+	errInvalidCount = errors.New("invalid counter value")
+	errEmptyName    = errors.New("empty metric name")
+	errEmptyBatch   = errors.New("empty batch")
+	errInvalidName  = errors.New("metric name must not contain braces")
+	errInvalidLabel = errors.New("invalid label name")
+	errAmbiguous    = errors.New("several series match, add a label filter")
+)
+
+// parseMetric собирает метрику из сегментов пути запроса
+func parseMetric(typeMetrics, name, rawValue string) (*models.Metrics, error) {
+	m := &models.Metrics{ID: name, MType: typeMetrics}
 	switch typeMetrics {
-	case "gauge":
+	case models.Gauge:
 		value, err := strconv.ParseFloat(rawValue, 64)
 		if err != nil {
-			http.Error(w, "invalid gauge value", http.StatusBadRequest)
-			return
+			return nil, errInvalidGauge
 		}
-		err = s.storage.SetGauge(name, value)
+		m.Value = &value
+	case models.Counter:
+		value, err := strconv.ParseInt(rawValue, 10, 64)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return nil, errInvalidCount
 		}
-	case "counter":
-		value, err := strconv.ParseInt(rawValue, 10, 64)
+		m.Delta = &value
+	default:
+		return nil, errInvalidType
+	}
+	return m, nil
+}
+
+// validateMetric проверяет имя, тип и наличие значения метрики.
+// withValue требует заполненного Delta или Value в зависимости от типа.
+func validateMetric(m *models.Metrics, withValue bool) error {
+	if m.ID == "" {
+		return errEmptyName
+	}
+	// фигурные скобки зарезервированы под метки в ключе ряда
+	if strings.ContainsAny(m.ID, "{}") {
+		return errInvalidName
+	}
+	for name := range m.Labels {
+		if !models.ValidLabelName(name) {
+			return fmt.Errorf("%w %q", errInvalidLabel, name)
+		}
+	}
+	switch m.MType {
+	case models.Gauge:
+		if withValue && m.Value == nil {
+			return errInvalidGauge
+		}
+	case models.Counter:
+		if withValue && m.Delta == nil {
+			return errInvalidCount
+		}
+	default:
+		return errInvalidType
+	}
+	return nil
+}
+
+// updateMetric сохраняет метрику и заполняет m актуальным значением из хранилища.
+// Ошибка возвращается, только если запись не применена: агент повторяет запросы с ответом 5xx.
+func (s *service) updateMetric(m *models.Metrics) error {
+	if err := validateMetric(m, true); err != nil {
+		return err
+	}
+	switch m.MType {
+	case models.Gauge:
+		if err := s.storage.SetGauge(m.Key(), *m.Value); err != nil {
+			return err
+		}
+	case models.Counter:
+		if err := s.storage.SetCounter(m.Key(), *m.Delta); err != nil {
+			return err
+		}
+	}
+	s.refreshMetric(m)
+	return nil
+}
+
+// refreshMetric заполняет m актуальным значением после успешной записи.
+// Если прочитать значение не удалось, в m остаётся переданное: запись уже применена,
+// и ответ с ошибкой привёл бы к повтору и удвоению счётчика.
+func (s *service) refreshMetric(m *models.Metrics) {
+	if err := s.getMetric(m); err != nil {
+		slog.Warn("failed to read metric after update", "metric", m.Key(), "error", err)
+	}
+}
+
+// getMetric заполняет m значением ряда с точно такими же именем и метками
+func (s *service) getMetric(m *models.Metrics) error {
+	if err := validateMetric(m, false); err != nil {
+		return err
+	}
+	switch m.MType {
+	case models.Gauge:
+		value, err := s.viewer.GetGauge(m.Key())
 		if err != nil {
-			http.Error(w, "invalid counter value", http.StatusBadRequest)
-			return
+			return err
 		}
-		err = s.storage.SetCounter(name, value)
+		m.Value, m.Delta = &value, nil
+	case models.Counter:
+		value, err := s.viewer.GetCounter(m.Key())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return err
+		}
+		m.Delta, m.Value = &value, nil
+	}
+	return nil
+}
+
+// findMetric ищет ряд по имени, считая метки m фильтром: подходит ряд,
+// у которого есть все перечисленные метки. Точное совпадение ключа имеет приоритет.
+// Запрос без меток при нескольких подходящих рядах возвращает первый по ключу ряд,
+// запрос с фильтром в этом случае получает errAmbiguous.
+// Найденный ряд записывается в m вместе со всеми своими метками.
+func (s *service) findMetric(m *models.Metrics) error {
+	err := s.getMetric(m)
+	if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	var keys []string
+	switch m.MType {
+	case models.Gauge:
+		gauges, err := s.viewer.GetMapGauge()
+		if err != nil {
+			return err
+		}
+		keys = slices.Collect(maps.Keys(gauges))
+	case models.Counter:
+		counters, err := s.viewer.GetMapCounter()
+		if err != nil {
+			return err
 		}
+		keys = slices.Collect(maps.Keys(counters))
+	}
+	slices.Sort(keys)
+
+	var found map[string]string
+	matches := 0
+	for _, key := range keys {
+		name, labels, err := models.ParseSeriesKey(key)
+		if err != nil || name != m.ID || !models.MatchLabels(labels, m.Labels) {
+			continue
+		}
+		if matches == 0 {
+			found = labels
+		}
+		matches++
+	}
+	switch {
+	case matches == 0:
+		return storage.ErrNotFound
+	case matches > 1 && len(m.Labels) > 0:
+		return errAmbiguous
 	default:
-		http.Error(w, "invalid metric type", http.StatusBadRequest)
-		return
+		m.Labels = found
+		return s.getMetric(m)
 	}
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+}
+
+// queryLabels читает метки из параметров запроса: ?host=web1&env=prod.
+// Параметры из reserved метками не считаются.
+func queryLabels(req *http.Request, reserved ...string) map[string]string {
+	var labels map[string]string
+	for k, v := range req.URL.Query() {
+		if slices.Contains(reserved, k) {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[k] = v[0]
+	}
+	return labels
+}
+
+// statusFromError сопоставляет ошибку с HTTP-статусом ответа
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, errEmptyName):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidType), errors.Is(err, errInvalidGauge), errors.Is(err, errInvalidCount),
+		errors.Is(err, errEmptyBatch), errors.Is(err, storage.ErrInvalidMetric),
+		errors.Is(err, errInvalidName), errors.Is(err, errInvalidLabel):
+		return http.StatusBadRequest
+	case errors.Is(err, errAmbiguous):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to encode response", "error", err)
+	}
 }
 
-func (s *service) GetMetric(w http.ResponseWriter, req *http.Request) {
+func (s *service) UpdateMetric(w http.ResponseWriter, req *http.Request) {
 	typeMetrics := req.PathValue("typeMetrics")
 	name := req.PathValue("name")
+	rawValue := req.PathValue("value")
+	slog.Debug("received metric", "type", typeMetrics, "name", name, "value", rawValue)
+	m, err := parseMetric(typeMetrics, name, rawValue)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m.Labels = queryLabels(req)
+	if err := s.updateMetric(m); err != nil {
+		http.Error(w, err.Error(), statusFromError(err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
 
-	switch typeMetrics {
-	case "gauge":
-		value, err := s.viewer.GetGauge(name)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		fmt.Fprint(w, strconv.FormatFloat(value, 'f', -1, 64))
-	case "counter":
-		value, err := s.viewer.GetCounter(name)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusNotFound)
+// UpdateMetricJSON принимает метрику в формате JSON и возвращает её актуальное значение
+func (s *service) UpdateMetricJSON(w http.ResponseWriter, req *http.Request) {
+	var m models.Metrics
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.updateMetric(&m); err != nil {
+		http.Error(w, err.Error(), statusFromError(err))
+		return
+	}
+	writeJSON(w, m)
+}
+
+// UpdateBatch принимает массив метрик в формате JSON и применяет его одной операцией.
+// В ответе возвращаются актуальные значения переданных метрик.
+func (s *service) UpdateBatch(w http.ResponseWriter, req *http.Request) {
+	var metrics []models.Metrics
+	if err := json.NewDecoder(req.Body).Decode(&metrics); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(metrics) == 0 {
+		http.Error(w, errEmptyBatch.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range metrics {
+		if err := validateMetric(&metrics[i], true); err != nil {
+			http.Error(w, fmt.Sprintf("metric %d: %v", i, err), statusFromError(err))
 			return
 		}
-		fmt.Fprintf(w, "%d\n", value)
-	default:
-		http.Error(w, "invalid metric type", http.StatusBadRequest)
+	}
+	// хранилища не знают о метках: ряд передаётся под своим ключом
+	series := make([]models.Metrics, len(metrics))
+	for i, m := range metrics {
+		series[i] = models.Metrics{ID: m.Key(), MType: m.MType, Delta: m.Delta, Value: m.Value}
+	}
+	if err := s.batch.UpdateBatch(series); err != nil {
+		http.Error(w, err.Error(), statusFromError(err))
+		return
+	}
+	for i := range metrics {
+		s.refreshMetric(&metrics[i])
+	}
+	writeJSON(w, metrics)
+}
+
+// GetMetric возвращает значение метрики текстом; параметры запроса фильтруют ряды по меткам
+func (s *service) GetMetric(w http.ResponseWriter, req *http.Request) {
+	m := &models.Metrics{
+		ID:     req.PathValue("name"),
+		MType:  req.PathValue("typeMetrics"),
+		Labels: queryLabels(req),
+	}
+	if err := s.findMetric(m); err != nil {
+		http.Error(w, err.Error(), statusFromError(err))
+		return
+	}
+	switch m.MType {
+	case models.Gauge:
+		fmt.Fprint(w, strconv.FormatFloat(*m.Value, 'f', -1, 64))
+	case models.Counter:
+		fmt.Fprintf(w, "%d\n", *m.Delta)
+	}
+}
+
+// GetMetricJSON возвращает значение метрики, запрошенной в формате JSON.
+// Метки в запросе работают как фильтр, в ответе возвращаются все метки ряда.
+func (s *service) GetMetricJSON(w http.ResponseWriter, req *http.Request) {
+	var m models.Metrics
+	if err := json.NewDecoder(req.Body).Decode(&m); err != nil {
+		http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.findMetric(&m); err != nil {
+		http.Error(w, err.Error(), statusFromError(err))
 		return
 	}
+	writeJSON(w, m)
 }
 
 func (s *service) GetIndex(w http.ResponseWriter, r *http.Request) {
@@ -113,6 +383,7 @@ func (s *service) GetIndex(w http.ResponseWriter, r *http.Request) {
 	tmpl := template.Must(template.New("index").Parse(indexTemplate))
 
 	// Рендерим шаблон
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := tmpl.Execute(w, data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
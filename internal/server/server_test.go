@@ -1,14 +1,17 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/iudanet/yp-metrics-go/internal/config"
 	"github.com/iudanet/yp-metrics-go/internal/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUpdateMetric(t *testing.T) {
@@ -129,3 +132,345 @@ func TestUpdateMetricSuccess(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricJSON(t *testing.T) {
+	store := storage.NewStorage()
+	cfg := config.NewServerConfig()
+	svc := NewService(store, cfg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(`POST /update/{$}`, svc.UpdateMetricJSON)
+	mux.HandleFunc(`POST /value/{$}`, svc.GetMetricJSON)
+
+	tests := []struct {
+		name       string
+		urlPath    string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "update_gauge",
+			urlPath:    "/update/",
+			body:       `{"id":"testGauge","type":"gauge","value":12.5}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"testGauge","type":"gauge","value":12.5}`,
+		},
+		{
+			name:       "update_counter",
+			urlPath:    "/update/",
+			body:       `{"id":"testCounter","type":"counter","delta":5}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"testCounter","type":"counter","delta":5}`,
+		},
+		{
+			name:       "update_counter_accumulates",
+			urlPath:    "/update/",
+			body:       `{"id":"testCounter","type":"counter","delta":7}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"testCounter","type":"counter","delta":12}`,
+		},
+		{
+			name:       "update_without_value",
+			urlPath:    "/update/",
+			body:       `{"id":"testGauge","type":"gauge"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "update_invalid_type",
+			urlPath:    "/update/",
+			body:       `{"id":"test","type":"histogram","value":1}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "update_empty_name",
+			urlPath:    "/update/",
+			body:       `{"id":"","type":"gauge","value":1}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "update_invalid_json",
+			urlPath:    "/update/",
+			body:       `{"id":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "value_gauge",
+			urlPath:    "/value/",
+			body:       `{"id":"testGauge","type":"gauge"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"testGauge","type":"gauge","value":12.5}`,
+		},
+		{
+			name:       "value_counter",
+			urlPath:    "/value/",
+			body:       `{"id":"testCounter","type":"counter"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"testCounter","type":"counter","delta":12}`,
+		},
+		{
+			name:       "value_not_found",
+			urlPath:    "/value/",
+			body:       `{"id":"unknown","type":"gauge"}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.urlPath, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code, "Response body: %v", w.Body.String())
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestUpdateBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "valid_batch",
+			body:       `[{"id":"c","type":"counter","delta":1},{"id":"c","type":"counter","delta":2},{"id":"g","type":"gauge","value":0.5}]`,
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":"c","type":"counter","delta":3},{"id":"c","type":"counter","delta":3},{"id":"g","type":"gauge","value":0.5}]`,
+		},
+		{
+			name:       "empty_batch",
+			body:       `[]`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid_metric_in_batch",
+			body:       `[{"id":"c","type":"counter","delta":1},{"id":"g","type":"gauge"}]`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid_json",
+			body:       `{"id":"c"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewStorage()
+			svc := NewService(store, config.NewServerConfig())
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(`POST /updates/{$}`, svc.UpdateBatch)
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code, "Response body: %v", w.Body.String())
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			} else {
+				counters, err := store.GetMapCounter()
+				assert.NoError(t, err)
+				assert.Empty(t, counters, "failed batch must not change storage")
+			}
+		})
+	}
+}
+
+func TestLabels(t *testing.T) {
+	store := storage.NewStorage()
+	svc := NewService(store, config.NewServerConfig())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(`POST /update/{typeMetrics}/{name}/{value}`, svc.UpdateMetric)
+	mux.HandleFunc(`POST /update/{$}`, svc.UpdateMetricJSON)
+	mux.HandleFunc(`POST /updates/{$}`, svc.UpdateBatch)
+	mux.HandleFunc(`GET /value/{typeMetrics}/{name}`, svc.GetMetric)
+	mux.HandleFunc(`POST /value/{$}`, svc.GetMetricJSON)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/update/", `{"id":"Alloc","type":"gauge","value":1,"labels":{"host":"web1","env":"prod"}}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id":"Alloc","type":"gauge","value":1,"labels":{"host":"web1","env":"prod"}}`, w.Body.String())
+
+	w = do(http.MethodPost, "/updates/", `[{"id":"Alloc","type":"gauge","value":2,"labels":{"host":"web2","env":"prod"}}]`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = do(http.MethodPost, "/update/counter/PollCount/3?host=web1", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	gauges, err := store.GetMapGauge()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{
+		`Alloc{env="prod",host="web1"}`: 1,
+		`Alloc{env="prod",host="web2"}`: 2,
+	}, gauges)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "filter_by_query", method: http.MethodGet, target: "/value/gauge/Alloc?host=web2", wantStatus: http.StatusOK, wantBody: "2"},
+		{name: "ambiguous", method: http.MethodGet, target: "/value/gauge/Alloc?env=prod", wantStatus: http.StatusConflict},
+		{name: "without_filter_first_series", method: http.MethodGet, target: "/value/gauge/Alloc", wantStatus: http.StatusOK, wantBody: "1"},
+		{name: "no_match", method: http.MethodGet, target: "/value/gauge/Alloc?host=web3", wantStatus: http.StatusNotFound},
+		{name: "single_series_without_filter", method: http.MethodGet, target: "/value/counter/PollCount", wantStatus: http.StatusOK, wantBody: "3\n"},
+		{
+			name:       "json_filter",
+			method:     http.MethodPost,
+			target:     "/value/",
+			body:       `{"id":"Alloc","type":"gauge","labels":{"host":"web1"}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"Alloc","type":"gauge","value":1,"labels":{"host":"web1","env":"prod"}}`,
+		},
+		{
+			name:       "invalid_label_name",
+			method:     http.MethodPost,
+			target:     "/update/",
+			body:       `{"id":"Alloc","type":"gauge","value":1,"labels":{"bad-name":"x"}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "braces_in_name",
+			method:     http.MethodPost,
+			target:     "/update/",
+			body:       `{"id":"Alloc{x}","type":"gauge","value":1}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.method, tt.target, tt.body)
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantBody == "" {
+				return
+			}
+			if strings.HasPrefix(tt.wantBody, "{") {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			} else {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLabelFreeLookupSeveralAgents(t *testing.T) {
+	tests := []struct {
+		name     string
+		series   map[string]float64
+		wantBody string
+	}{
+		{
+			name: "first_series_by_key",
+			series: map[string]float64{
+				`Alloc{agent_id="b",host="web2"}`: 2,
+				`Alloc{agent_id="a",host="web1"}`: 1,
+			},
+			wantBody: "1",
+		},
+		{
+			name: "label_free_series_wins",
+			series: map[string]float64{
+				`Alloc{agent_id="a",host="web1"}`: 1,
+				`Alloc{agent_id="b",host="web2"}`: 2,
+				"Alloc":                           3,
+			},
+			wantBody: "3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewStorage()
+			for key, value := range tt.series {
+				require.NoError(t, store.SetGauge(key, value))
+			}
+			svc := NewService(store, config.NewServerConfig())
+			mux := http.NewServeMux()
+			mux.HandleFunc(`GET /value/{typeMetrics}/{name}`, svc.GetMetric)
+
+			// ответ не должен зависеть от порядка обхода map в хранилище
+			for range 10 {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/value/gauge/Alloc", nil))
+				require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+// unreadableRepo применяет записи, но не отдаёт значения
+type unreadableRepo struct {
+	storage.Repository
+}
+
+func (unreadableRepo) GetCounter(string) (int64, error) {
+	return 0, errors.New("read failed")
+}
+
+func (unreadableRepo) GetGauge(string) (float64, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestUpdateAppliedDespiteReadError(t *testing.T) {
+	store := storage.NewStorage()
+	svc := NewService(unreadableRepo{store}, config.NewServerConfig())
+	mux := http.NewServeMux()
+	mux.HandleFunc(`POST /update/{$}`, svc.UpdateMetricJSON)
+	mux.HandleFunc(`POST /updates/{$}`, svc.UpdateBatch)
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		wantBody string
+	}{
+		{
+			name:     "single",
+			target:   "/update/",
+			body:     `{"id":"PollCount","type":"counter","delta":2}`,
+			wantBody: `{"id":"PollCount","type":"counter","delta":2}`,
+		},
+		{
+			name:     "batch",
+			target:   "/updates/",
+			body:     `[{"id":"PollCount","type":"counter","delta":3}]`,
+			wantBody: `[{"id":"PollCount","type":"counter","delta":3}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body)))
+			// запись уже применена: 5xx заставил бы агента повторить её
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.JSONEq(t, tt.wantBody, w.Body.String())
+		})
+	}
+
+	counter, err := store.GetCounter("PollCount")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), counter)
+}
@@ -0,0 +1,63 @@
+package sign
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// metadataKey — имя подписи в метаданных gRPC (ключи метаданных всегда в нижнем регистре)
+var metadataKey = strings.ToLower(HeaderName)
+
+// marshal сериализует сообщение детерминированно, чтобы клиент и сервер получили одинаковые байты
+func marshal(msg any) ([]byte, error) {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil, status.Error(codes.Internal, "message is not a protobuf message")
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+}
+
+// UnaryClientInterceptor подписывает каждый запрос HMAC-SHA256 от сериализованного сообщения.
+// При пустом ключе запросы не подписываются.
+func UnaryClientInterceptor(key string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if key == "" {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		data, err := marshal(req)
+		if err != nil {
+			return err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, metadataKey, Sum(data, key))
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// UnaryServerInterceptor отклоняет запросы без верной подписи с кодом Unauthenticated.
+// При пустом ключе проверка отключена.
+func UnaryServerInterceptor(key string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if key == "" {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(metadataKey)
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing signature")
+		}
+		data, err := marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		if !Verify(data, key, values[0]) {
+			return nil, status.Error(codes.Unauthenticated, "invalid signature")
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,84 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/iudanet/yp-metrics-go/internal/httpbody"
+)
+
+// HeaderName — заголовок с подписью тела запроса или ответа
+const HeaderName = "HashSHA256"
+
+// Sum возвращает HMAC-SHA256 от data в шестнадцатеричном виде
+func Sum(data []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись hash для data за постоянное время
+func Verify(data []byte, key, hash string) bool {
+	expected, err := hex.DecodeString(hash)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// Middleware проверяет подпись тел входящих запросов и подписывает ответы.
+// Запросы GET и HEAD не проверяются, так как не несут тела.
+// При пустом ключе обработчик возвращается без изменений.
+func Middleware(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if key == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				body, ok := httpbody.Read(w, r)
+				if !ok {
+					return
+				}
+				hash := r.Header.Get(HeaderName)
+				if hash == "" {
+					http.Error(w, "missing signature", http.StatusBadRequest)
+					return
+				}
+				if !Verify(body, key, hash) {
+					http.Error(w, "invalid signature", http.StatusBadRequest)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			sw := &signWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			w.Header().Set(HeaderName, Sum(sw.body.Bytes(), key))
+			w.WriteHeader(sw.status)
+			_, _ = w.Write(sw.body.Bytes())
+		})
+	}
+}
+
+// signWriter буферизует ответ, так как подпись должна уйти в заголовке до тела
+type signWriter struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (s *signWriter) WriteHeader(statusCode int) {
+	s.status = statusCode
+}
+
+func (s *signWriter) Write(p []byte) (int, error) {
+	return s.body.Write(p)
+}
@@ -0,0 +1,110 @@
+package sign
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iudanet/yp-metrics-go/internal/httpbody"
+)
+
+func TestSumVerify(t *testing.T) {
+	data := []byte(`{"id":"test","type":"counter","delta":1}`)
+	hash := Sum(data, "secret")
+
+	assert.True(t, Verify(data, "secret", hash))
+	assert.False(t, Verify(data, "other", hash))
+	assert.False(t, Verify([]byte("changed"), "secret", hash))
+	assert.False(t, Verify(data, "secret", "not-hex"))
+}
+
+func TestMiddleware(t *testing.T) {
+	const (
+		key  = "secret"
+		body = `{"id":"test","type":"counter","delta":1}`
+	)
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		method     string
+		hash       string
+		wantStatus int
+		wantSigned bool
+	}{
+		{
+			name:       "valid_signature",
+			key:        key,
+			method:     http.MethodPost,
+			hash:       Sum([]byte(body), key),
+			wantStatus: http.StatusOK,
+			wantSigned: true,
+		},
+		{
+			name:       "missing_signature",
+			key:        key,
+			method:     http.MethodPost,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid_signature",
+			key:        key,
+			method:     http.MethodPost,
+			hash:       Sum([]byte(body), "other"),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "get_not_verified",
+			key:        key,
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantSigned: true,
+		},
+		{
+			name:       "no_key",
+			method:     http.MethodPost,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/update/", strings.NewReader(body))
+			if tt.hash != "" {
+				req.Header.Set(HeaderName, tt.hash)
+			}
+			w := httptest.NewRecorder()
+
+			Middleware(tt.key)(echo).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantSigned {
+				assert.True(t, Verify(w.Body.Bytes(), key, w.Header().Get(HeaderName)))
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			} else {
+				assert.Empty(t, w.Header().Get(HeaderName))
+			}
+		})
+	}
+}
+
+func TestMiddlewareBodyLimit(t *testing.T) {
+	body := make([]byte, httpbody.MaxSize+1)
+	req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+	req.Header.Set(HeaderName, Sum(body, "secret"))
+	w := httptest.NewRecorder()
+	Middleware("secret")(http.NotFoundHandler()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -0,0 +1,194 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+// DroppedMetric — имя счётчика отброшенных строк в хранилище
+const DroppedMetric = "StatsdDroppedLines"
+
+// maxPacketSize — максимальный размер UDP-датаграммы
+const maxPacketSize = 65535
+
+var (
+	errInvalidLine = errors.New("invalid statsd line")
+	errUnsupported = errors.New("unsupported statsd type")
+)
+
+// Listener принимает метрики в формате StatsD по UDP и записывает их в хранилище.
+// Поддерживаются счётчики (c) и gauge (g), в том числе относительные "+1|g" и "-1|g".
+// Таймеры, гистограммы и множества отбрасываются и учитываются в DroppedMetric.
+type Listener struct {
+	repo    storage.Repository
+	dropped atomic.Int64
+	// trusted, если задана, ограничивает адреса отправителей датаграмм
+	trusted *net.IPNet
+}
+
+// Option задаёт необязательные параметры приёмника
+type Option func(*Listener)
+
+// WithTrustedSubnet отбрасывает датаграммы, отправленные не из subnet
+func WithTrustedSubnet(subnet *net.IPNet) Option {
+	return func(l *Listener) {
+		l.trusted = subnet
+	}
+}
+
+func NewListener(repo storage.Repository, opts ...Option) *Listener {
+	l := &Listener{repo: repo}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Dropped возвращает число отброшенных строк с момента запуска
+func (l *Listener) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Serve читает датаграммы из conn до отмены ctx и закрывает conn
+func (l *Listener) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read statsd packet: %w", err)
+		}
+		if !l.allowed(addr) {
+			slog.Warn("statsd: dropped packet from untrusted address", "addr", addr.String())
+			continue
+		}
+		l.HandlePacket(buf[:n])
+	}
+}
+
+// allowed проверяет отправителя датаграммы по доверенной подсети
+func (l *Listener) allowed(addr net.Addr) bool {
+	if l.trusted == nil {
+		return true
+	}
+	udp, ok := addr.(*net.UDPAddr)
+	return ok && l.trusted.Contains(udp.IP)
+}
+
+// HandlePacket применяет все строки датаграммы
+func (l *Listener) HandlePacket(packet []byte) {
+	var dropped int64
+	for _, line := range bytes.Split(packet, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := l.handleLine(string(line)); err != nil {
+			slog.Warn("statsd: dropped line", "line", string(line), "error", err)
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return
+	}
+	l.dropped.Add(dropped)
+	if err := l.repo.SetCounter(DroppedMetric, dropped); err != nil {
+		slog.Error("statsd: failed to count dropped lines", "error", err)
+	}
+}
+
+func (l *Listener) handleLine(line string) error {
+	m, err := parseLine(line)
+	if err != nil {
+		return err
+	}
+	switch m.kind {
+	case "c":
+		return l.repo.SetCounter(m.name, int64(math.Round(m.value/m.rate)))
+	case "g":
+		value := m.value
+		if m.relative {
+			current, err := l.repo.GetGauge(m.name)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return err
+			}
+			value += current
+		}
+		return l.repo.SetGauge(m.name, value)
+	default:
+		return fmt.Errorf("%w %q", errUnsupported, m.kind)
+	}
+}
+
+// metric — разобранная строка StatsD
+type metric struct {
+	name     string
+	value    float64
+	kind     string
+	rate     float64
+	relative bool
+}
+
+// parseLine разбирает строку вида name:value|type[|@rate][|#tags]
+func parseLine(line string) (metric, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return metric{}, errInvalidLine
+	}
+	// фигурные скобки зарезервированы под метки в ключе ряда: такое имя
+	// перезаписало бы ряд с метками другого агента или не читалось бы вовсе
+	if strings.ContainsAny(name, "{}") {
+		return metric{}, fmt.Errorf("%w: name %q", errInvalidLine, name)
+	}
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 || parts[0] == "" {
+		return metric{}, errInvalidLine
+	}
+
+	m := metric{name: name, kind: parts[1], rate: 1}
+	switch m.kind {
+	case "c", "g":
+	case "ms", "h", "d", "s":
+		return metric{}, fmt.Errorf("%w %q", errUnsupported, m.kind)
+	default:
+		return metric{}, fmt.Errorf("%w: type %q", errInvalidLine, m.kind)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return metric{}, fmt.Errorf("%w: value %q", errInvalidLine, parts[0])
+	}
+	m.value = value
+	// у gauge знак означает изменение текущего значения, а не новое значение
+	m.relative = m.kind == "g" && (parts[0][0] == '+' || parts[0][0] == '-')
+
+	for _, ext := range parts[2:] {
+		// теги не поддерживаются хранилищем и пропускаются
+		if !strings.HasPrefix(ext, "@") {
+			continue
+		}
+		rate, err := strconv.ParseFloat(ext[1:], 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return metric{}, fmt.Errorf("%w: sample rate %q", errInvalidLine, ext)
+		}
+		m.rate = rate
+	}
+	return m, nil
+}
@@ -0,0 +1,118 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/storage"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected metric
+		wantErr  error
+	}{
+		{line: "hits:1|c", expected: metric{name: "hits", value: 1, kind: "c", rate: 1}},
+		{line: "hits:2|c|@0.5", expected: metric{name: "hits", value: 2, kind: "c", rate: 0.5}},
+		{line: "temp:3.2|g|#env:prod", expected: metric{name: "temp", value: 3.2, kind: "g", rate: 1}},
+		{line: "temp:-1.5|g", expected: metric{name: "temp", value: -1.5, kind: "g", rate: 1, relative: true}},
+		{line: "latency:320|ms", wantErr: errUnsupported},
+		{line: "users:42|s", wantErr: errUnsupported},
+		{line: "hits", wantErr: errInvalidLine},
+		{line: ":1|c", wantErr: errInvalidLine},
+		{line: "hits:1", wantErr: errInvalidLine},
+		{line: "hits:abc|c", wantErr: errInvalidLine},
+		{line: "hits:1|x", wantErr: errInvalidLine},
+		{line: "hits:1|c|@0", wantErr: errInvalidLine},
+		{line: `Alloc{agent_id="web1",host="web1"}:7|g`, wantErr: errInvalidLine},
+		{line: "bad{x:1|c", wantErr: errInvalidLine},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			m, err := parseLine(tt.line)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m)
+		})
+	}
+}
+
+func TestHandlePacket(t *testing.T) {
+	repo := storage.NewStorage()
+	l := NewListener(repo)
+
+	require.NoError(t, repo.SetGauge(`Alloc{host="web1"}`, 1))
+	l.HandlePacket([]byte("hits:1|c\nhits:2|c|@0.5\ntemp:10|g\ntemp:+2.5|g\nlatency:5|ms\ngarbage\n\n" +
+		"Alloc{host=\"web1\"}:7|g\nbad{x:1|c\n"))
+
+	hits, err := repo.GetCounter("hits")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), hits)
+
+	temp, err := repo.GetGauge("temp")
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, temp)
+
+	// строки с фигурными скобками в имени не трогают ряды с метками
+	alloc, err := repo.GetGauge(`Alloc{host="web1"}`)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, alloc)
+	_, err = repo.GetCounter("bad{x")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	assert.Equal(t, int64(4), l.Dropped())
+	dropped, err := repo.GetCounter(DroppedMetric)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), dropped)
+}
+
+func TestServe(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	repo := storage.NewStorage()
+	l := NewListener(repo)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Serve(ctx, conn)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("requests:3|c"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		v, err := repo.GetCounter("requests")
+		return err == nil && v == 3
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not stop after cancel")
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	l := NewListener(storage.NewStorage(), WithTrustedSubnet(subnet))
+
+	assert.True(t, l.allowed(&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 8125}))
+	assert.False(t, l.allowed(&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8125}))
+	assert.True(t, NewListener(storage.NewStorage()).allowed(&net.UDPAddr{IP: net.ParseIP("127.0.0.1")}))
+}
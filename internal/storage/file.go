@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+// NewFileStorage создаёт хранилище в памяти, которое сохраняет снимки метрик в файл.
+// При storeInterval == 0 снимок пишется синхронно после каждого изменения,
+// иначе периодически в Run. При restore == true метрики загружаются из файла.
+func NewFileStorage(path string, storeInterval time.Duration, restore bool) (*fileStorage, error) {
+	f := &fileStorage{
+		memStorage:    NewStorage(),
+		path:          path,
+		storeInterval: storeInterval,
+	}
+	if restore {
+		if err := f.Load(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+type fileStorage struct {
+	*memStorage
+	path          string
+	storeInterval time.Duration
+	saveMutex     sync.Mutex
+	// writeMutex упорядочивает синхронные записи, чтобы откат не затёр чужое изменение
+	writeMutex sync.Mutex
+}
+
+func (f *fileStorage) SetCounter(name string, value int64) error {
+	return f.apply(func() error {
+		return f.memStorage.SetCounter(name, value)
+	})
+}
+
+func (f *fileStorage) SetGauge(name string, value float64) error {
+	return f.apply(func() error {
+		return f.memStorage.SetGauge(name, value)
+	})
+}
+
+func (f *fileStorage) IncrCounter(name string) error {
+	return f.apply(func() error {
+		return f.memStorage.IncrCounter(name)
+	})
+}
+
+func (f *fileStorage) UpdateBatch(metrics []models.Metrics) error {
+	return f.apply(func() error {
+		return f.memStorage.UpdateBatch(metrics)
+	})
+}
+
+// apply выполняет запись в памяти. В синхронном режиме сразу сохраняет снимок
+// и при ошибке сохранения возвращает хранилище к состоянию до записи:
+// клиент получает ошибку, и повтор запроса не удваивает счётчики.
+func (f *fileStorage) apply(write func() error) error {
+	if f.storeInterval > 0 {
+		return write()
+	}
+	f.writeMutex.Lock()
+	defer f.writeMutex.Unlock()
+
+	gauges, counters := f.clone()
+	if err := write(); err != nil {
+		return err
+	}
+	if err := f.Save(); err != nil {
+		f.restore(gauges, counters)
+		return err
+	}
+	return nil
+}
+
+// Run периодически сохраняет снимок до отмены контекста
+func (f *fileStorage) Run(ctx context.Context) {
+	if f.storeInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(f.storeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Save(); err != nil {
+				slog.Error("failed to save metrics", "error", err)
+			}
+		}
+	}
+}
+
+// Close записывает финальный снимок
+func (f *fileStorage) Close() error {
+	return f.Save()
+}
+
+// Save атомарно записывает снимок метрик: во временный файл с последующим переименованием
+func (f *fileStorage) Save() error {
+	f.saveMutex.Lock()
+	defer f.saveMutex.Unlock()
+
+	data, err := json.Marshal(f.snapshot())
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace metrics file: %w", err)
+	}
+	return nil
+}
+
+// Load заменяет содержимое хранилища снимком из файла. Отсутствие файла не является ошибкой.
+func (f *fileStorage) Load() error {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read metrics file: %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var metrics []models.Metrics
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return fmt.Errorf("failed to parse metrics file: %w", err)
+	}
+	if err := validateBatch(metrics); err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.gauge = make(map[string]float64)
+	f.counter = make(map[string]int64)
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			f.gauge[metric.ID] = *metric.Value
+		case models.Counter:
+			f.counter[metric.ID] = *metric.Delta
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+)
+
+func TestFileStorage(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, path string)
+	}{
+		{
+			name: "test_restore_after_close",
+			fn: func(t *testing.T, path string) {
+				s, err := NewFileStorage(path, time.Hour, true)
+				require.NoError(t, err)
+				require.NoError(t, s.SetCounter("counter", 10))
+				require.NoError(t, s.SetGauge("gauge", 1.5))
+				require.NoError(t, s.Close())
+
+				restored, err := NewFileStorage(path, time.Hour, true)
+				require.NoError(t, err)
+				counter, err := restored.GetCounter("counter")
+				require.NoError(t, err)
+				assert.Equal(t, int64(10), counter)
+				gauge, err := restored.GetGauge("gauge")
+				require.NoError(t, err)
+				assert.Equal(t, 1.5, gauge)
+			},
+		},
+		{
+			name: "test_sync_write",
+			fn: func(t *testing.T, path string) {
+				s, err := NewFileStorage(path, 0, false)
+				require.NoError(t, err)
+				require.NoError(t, s.SetCounter("counter", 3))
+
+				restored, err := NewFileStorage(path, 0, true)
+				require.NoError(t, err)
+				counter, err := restored.GetCounter("counter")
+				require.NoError(t, err)
+				assert.Equal(t, int64(3), counter)
+			},
+		},
+		{
+			name: "test_no_restore",
+			fn: func(t *testing.T, path string) {
+				s, err := NewFileStorage(path, 0, false)
+				require.NoError(t, err)
+				require.NoError(t, s.SetCounter("counter", 3))
+
+				fresh, err := NewFileStorage(path, 0, false)
+				require.NoError(t, err)
+				_, err = fresh.GetCounter("counter")
+				assert.ErrorIs(t, err, ErrNotFound)
+			},
+		},
+		{
+			name: "test_missing_file",
+			fn: func(t *testing.T, path string) {
+				s, err := NewFileStorage(path, time.Hour, true)
+				require.NoError(t, err)
+				counters, err := s.GetMapCounter()
+				require.NoError(t, err)
+				assert.Empty(t, counters)
+			},
+		},
+		{
+			name: "test_broken_file",
+			fn: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, []byte("{broken"), 0o600))
+				_, err := NewFileStorage(path, time.Hour, true)
+				assert.Error(t, err)
+			},
+		},
+		{
+			name: "test_periodic_save",
+			fn: func(t *testing.T, path string) {
+				s, err := NewFileStorage(path, 10*time.Millisecond, false)
+				require.NoError(t, err)
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					s.Run(ctx)
+				}()
+				// сохранение не должно писать во временный каталог после завершения теста
+				defer func() {
+					cancel()
+					<-done
+				}()
+
+				require.NoError(t, s.SetGauge("gauge", 2))
+				assert.Eventually(t, func() bool {
+					_, err := os.Stat(path)
+					return err == nil
+				}, time.Second, 10*time.Millisecond)
+			},
+		},
+		{
+			name: "test_rollback_on_failed_save",
+			fn: func(t *testing.T, path string) {
+				s, err := NewFileStorage(path, 0, false)
+				require.NoError(t, err)
+				require.NoError(t, s.SetCounter("counter", 3))
+				require.NoError(t, s.SetGauge("gauge", 1))
+
+				// каталога нет: сохранение снимка не удаётся
+				s.path = filepath.Join(filepath.Dir(path), "missing", "metrics.json")
+				assert.Error(t, s.SetCounter("counter", 5))
+				assert.Error(t, s.SetGauge("gauge", 2))
+				delta := int64(1)
+				assert.Error(t, s.UpdateBatch([]models.Metrics{{ID: "other", MType: models.Counter, Delta: &delta}}))
+
+				counters, err := s.GetMapCounter()
+				require.NoError(t, err)
+				assert.Equal(t, map[string]int64{"counter": 3}, counters, "a failed write must not be applied")
+				gauges, err := s.GetMapGauge()
+				require.NoError(t, err)
+				assert.Equal(t, map[string]float64{"gauge": 1}, gauges)
+			},
+		},
+		{
+			name: "test_no_temp_files_left",
+			fn: func(t *testing.T, path string) {
+				s, err := NewFileStorage(path, 0, false)
+				require.NoError(t, err)
+				require.NoError(t, s.SetGauge("gauge", 2))
+
+				entries, err := os.ReadDir(filepath.Dir(path))
+				require.NoError(t, err)
+				assert.Len(t, entries, 1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metrics.json")
+			tt.fn(t, path)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresMigrations — схема хранилища PostgreSQL, новые версии добавляются в конец
+var postgresMigrations = []migration{
+	{
+		version: 1,
+		query: `CREATE TABLE IF NOT EXISTS counters (
+			name  TEXT PRIMARY KEY,
+			value BIGINT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS gauges (
+			name  TEXT PRIMARY KEY,
+			value DOUBLE PRECISION NOT NULL
+		);`,
+	},
+}
+
+// NewPostgresStorage подключается к PostgreSQL по DSN и применяет миграции
+func NewPostgresStorage(ctx context.Context, dsn string, opts ...SQLOption) (*sqlStorage, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	s := newSQLStorage(db, isPostgresTransient, opts...)
+	if err := s.Ping(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := s.Migrate(ctx, postgresMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// isPostgresTransient отбирает ошибки, при которых запрос заведомо не дошёл до сервера.
+// Таймауты и обрывы уже установленного соединения не повторяются: запись могла
+// примениться, и повтор удвоил бы приращение счётчика.
+func isPostgresTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08001 и 08004 — соединение не установлено, 57P03 — cannot_connect_now
+		switch pgErr.Code {
+		case "08001", "08004", "57P03":
+			return true
+		}
+		return false
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	// database/sql и драйвер возвращают ErrBadConn, только если запрос не отправлялся
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -2,13 +2,18 @@ package storage
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"sort"
 	"sync"
 
+	"github.com/iudanet/yp-metrics-go/internal/models"
 	"github.com/iudanet/yp-metrics-go/internal/utils"
 )
 
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound      = errors.New("not found")
+	ErrInvalidMetric = errors.New("invalid metric")
 )
 
 // MetricReader определяет методы для чтения метрик
@@ -25,6 +30,11 @@ type MetricWriter interface {
 	SetGauge(string, float64) error
 }
 
+// MetricBatchWriter применяет набор метрик атомарно: либо все, либо ни одной
+type MetricBatchWriter interface {
+	UpdateBatch([]models.Metrics) error
+}
+
 // CounterIncrementer выделяет специфическую операцию инкремента
 type CounterIncrementer interface {
 	IncrCounter(string) error
@@ -34,6 +44,7 @@ type CounterIncrementer interface {
 type Repository interface {
 	MetricReader
 	MetricWriter
+	MetricBatchWriter
 	CounterIncrementer
 }
 
@@ -70,6 +81,39 @@ func (m *memStorage) SetGauge(name string, value float64) error {
 	return nil
 }
 
+// UpdateBatch проверяет все метрики и применяет их под одной блокировкой
+func (m *memStorage) UpdateBatch(metrics []models.Metrics) error {
+	if err := validateBatch(metrics); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			m.gauge[metric.ID] = utils.Round(*metric.Value, 3)
+		case models.Counter:
+			m.counter[metric.ID] += *metric.Delta
+		}
+	}
+	return nil
+}
+
+func validateBatch(metrics []models.Metrics) error {
+	for _, metric := range metrics {
+		switch {
+		case metric.ID == "":
+			return fmt.Errorf("%w: empty name", ErrInvalidMetric)
+		case metric.MType == models.Gauge && metric.Value == nil,
+			metric.MType == models.Counter && metric.Delta == nil:
+			return fmt.Errorf("%w: %s has no value", ErrInvalidMetric, metric.ID)
+		case metric.MType != models.Gauge && metric.MType != models.Counter:
+			return fmt.Errorf("%w: %s has unknown type %q", ErrInvalidMetric, metric.ID, metric.MType)
+		}
+	}
+	return nil
+}
+
 func (m *memStorage) IncrCounter(name string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -80,13 +124,13 @@ func (m *memStorage) IncrCounter(name string) error {
 func (m *memStorage) GetMapCounter() (map[string]int64, error) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
-	return m.counter, nil
+	return maps.Clone(m.counter), nil
 }
 
 func (m *memStorage) GetMapGauge() (map[string]float64, error) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
-	return m.gauge, nil
+	return maps.Clone(m.gauge), nil
 }
 
 func (m *memStorage) GetCounter(name string) (int64, error) {
@@ -109,3 +153,37 @@ func (m *memStorage) GetGauge(name string) (float64, error) {
 	}
 	return value, nil
 }
+
+// clone возвращает копии всех значений для последующего restore
+func (m *memStorage) clone() (map[string]float64, map[string]int64) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return maps.Clone(m.gauge), maps.Clone(m.counter)
+}
+
+// restore заменяет содержимое хранилища значениями, полученными из clone
+func (m *memStorage) restore(gauges map[string]float64, counters map[string]int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.gauge, m.counter = gauges, counters
+}
+
+// snapshot возвращает метрики, отсортированные по типу и имени
+func (m *memStorage) snapshot() []models.Metrics {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	metrics := make([]models.Metrics, 0, len(m.counter)+len(m.gauge))
+	for name, value := range m.counter {
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Counter, Delta: &value})
+	}
+	for name, value := range m.gauge {
+		metrics = append(metrics, models.Metrics{ID: name, MType: models.Gauge, Value: &value})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].MType != metrics[j].MType {
+			return metrics[i].MType < metrics[j].MType
+		}
+		return metrics[i].ID < metrics[j].ID
+	})
+	return metrics
+}
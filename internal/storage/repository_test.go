@@ -3,61 +3,98 @@ package storage
 import (
 	"testing"
 
+	"github.com/iudanet/yp-metrics-go/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func TestMemStorage(t *testing.T) {
-	tests := []struct {
-		name string
-		fn   func(t *testing.T, s Repository)
-	}{
-		{
-			name: "test_counter_operations",
-			fn: func(t *testing.T, s Repository) {
-				// Set counter
-				err := s.SetCounter("test", 10)
-				require.NoError(t, err)
+// repositoryTests — общие проверки для всех реализаций Repository
+var repositoryTests = []struct {
+	name string
+	fn   func(t *testing.T, s Repository)
+}{
+	{
+		name: "test_counter_operations",
+		fn: func(t *testing.T, s Repository) {
+			// Set counter
+			err := s.SetCounter("test", 10)
+			require.NoError(t, err)
 
-				// Increment counter
-				err = s.IncrCounter("test")
-				require.NoError(t, err)
+			// Increment counter
+			err = s.IncrCounter("test")
+			require.NoError(t, err)
 
-				// Get counter map
-				counters, err := s.GetMapCounter()
-				require.NoError(t, err)
-				assert.Equal(t, int64(11), counters["test"])
-			},
+			// Get counter map
+			counters, err := s.GetMapCounter()
+			require.NoError(t, err)
+			assert.Equal(t, int64(11), counters["test"])
 		},
-		{
-			name: "test_gauge_operations",
-			fn: func(t *testing.T, s Repository) {
-				// Set gauge
-				err := s.SetGauge("test", 10.5)
-				require.NoError(t, err)
+	},
+	{
+		name: "test_gauge_operations",
+		fn: func(t *testing.T, s Repository) {
+			// Set gauge
+			err := s.SetGauge("test", 10.5)
+			require.NoError(t, err)
 
-				// Get gauge map
-				gauges, err := s.GetMapGauge()
-				require.NoError(t, err)
-				assert.Equal(t, 10.5, gauges["test"])
-			},
+			// Get gauge map
+			gauges, err := s.GetMapGauge()
+			require.NoError(t, err)
+			assert.Equal(t, 10.5, gauges["test"])
 		},
-		{
-			name: "test_concurrent_access",
-			fn: func(t *testing.T, s Repository) {
-				// Simulate concurrent access
-				go func() {
-					_ = s.SetGauge("concurrent", 1.0)
-				}()
-				go func() {
-					_ = s.SetCounter("concurrent", 1)
-				}()
-				// Check no panic occurs
-			},
+	},
+	{
+		name: "test_batch_update",
+		fn: func(t *testing.T, s Repository) {
+			delta := int64(5)
+			value := 1.5
+			err := s.UpdateBatch([]models.Metrics{
+				{ID: "batchCounter", MType: models.Counter, Delta: &delta},
+				{ID: "batchCounter", MType: models.Counter, Delta: &delta},
+				{ID: "batchGauge", MType: models.Gauge, Value: &value},
+			})
+			require.NoError(t, err)
+
+			counter, err := s.GetCounter("batchCounter")
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), counter)
+
+			gauge, err := s.GetGauge("batchGauge")
+			require.NoError(t, err)
+			assert.Equal(t, 1.5, gauge)
 		},
-	}
+	},
+	{
+		name: "test_batch_update_is_atomic",
+		fn: func(t *testing.T, s Repository) {
+			delta := int64(5)
+			err := s.UpdateBatch([]models.Metrics{
+				{ID: "batchCounter", MType: models.Counter, Delta: &delta},
+				{ID: "batchGauge", MType: models.Gauge},
+			})
+			require.ErrorIs(t, err, ErrInvalidMetric)
 
-	for _, tt := range tests {
+			_, err = s.GetCounter("batchCounter")
+			assert.ErrorIs(t, err, ErrNotFound)
+		},
+	},
+	{
+		name: "test_concurrent_access",
+		fn: func(t *testing.T, s Repository) {
+			// Simulate concurrent access
+			go func() {
+				_ = s.SetGauge("concurrent", 1.0)
+			}()
+			go func() {
+				_ = s.SetCounter("concurrent", 1)
+			}()
+			// Check no panic occurs
+		},
+	},
+}
+
+func TestMemStorage(t *testing.T) {
+	for _, tt := range repositoryTests {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := NewStorage()
 			tt.fn(t, storage)
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iudanet/yp-metrics-go/internal/models"
+	"github.com/iudanet/yp-metrics-go/internal/retry"
+	"github.com/iudanet/yp-metrics-go/internal/utils"
+)
+
+// queryTimeout ограничивает время одного обращения к базе данных
+const queryTimeout = 5 * time.Second
+
+// migration описывает одну версию схемы базы данных
+type migration struct {
+	version int
+	query   string
+}
+
+// sqlStorage реализует Repository поверх database/sql.
+// Счётчики накапливаются, gauge перезаписываются — так же, как в memStorage.
+type sqlStorage struct {
+	db    *sql.DB
+	retry retry.Policy
+}
+
+// SQLOption задаёт необязательные параметры хранилища в базе данных
+type SQLOption func(*sqlStorage)
+
+// WithRetryDelays задаёт паузы между повторами запросов при временных ошибках соединения
+func WithRetryDelays(delays []time.Duration) SQLOption {
+	return func(s *sqlStorage) {
+		s.retry.Delays = delays
+	}
+}
+
+// newSQLStorage создаёт хранилище; transient отбирает ошибки драйвера, которые стоит повторить
+func newSQLStorage(db *sql.DB, transient func(error) bool, opts ...SQLOption) *sqlStorage {
+	s := &sqlStorage{
+		db: db,
+		retry: retry.Policy{
+			Delays:    retry.DefaultDelays,
+			Retriable: transient,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// withRetry выполняет fn с отдельным таймаутом на каждую попытку и повторяет временные ошибки
+func (s *sqlStorage) withRetry(fn func(ctx context.Context) error) error {
+	return s.retry.Do(context.Background(), func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+// Migrate применяет недостающие миграции, каждую в своей транзакции
+func (s *sqlStorage) Migrate(ctx context.Context, migrations []migration) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if err := s.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", m.version, err)
+		}
+	}
+	return nil
+}
+
+func (s *sqlStorage) applyMigration(ctx context.Context, m migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var applied bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, m.query); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const (
+	upsertCounterQuery = `INSERT INTO counters (name, value) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = counters.value + excluded.value`
+	upsertGaugeQuery = `INSERT INTO gauges (name, value) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value`
+)
+
+func (s *sqlStorage) SetCounter(name string, value int64) error {
+	err := s.withRetry(func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, upsertCounterQuery, name, value)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set counter %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *sqlStorage) SetGauge(name string, value float64) error {
+	err := s.withRetry(func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, upsertGaugeQuery, name, utils.Round(value, 3))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set gauge %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *sqlStorage) IncrCounter(name string) error {
+	return s.SetCounter(name, 1)
+}
+
+// UpdateBatch применяет все метрики в одной транзакции
+func (s *sqlStorage) UpdateBatch(metrics []models.Metrics) error {
+	if err := validateBatch(metrics); err != nil {
+		return err
+	}
+	return s.withRetry(func(ctx context.Context) error {
+		return s.updateBatch(ctx, metrics)
+	})
+}
+
+func (s *sqlStorage) updateBatch(ctx context.Context, metrics []models.Metrics) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	counterStmt, err := tx.PrepareContext(ctx, upsertCounterQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare counter statement: %w", err)
+	}
+	defer counterStmt.Close()
+	gaugeStmt, err := tx.PrepareContext(ctx, upsertGaugeQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare gauge statement: %w", err)
+	}
+	defer gaugeStmt.Close()
+
+	for _, metric := range metrics {
+		switch metric.MType {
+		case models.Gauge:
+			_, err = gaugeStmt.ExecContext(ctx, metric.ID, utils.Round(*metric.Value, 3))
+		case models.Counter:
+			_, err = counterStmt.ExecContext(ctx, metric.ID, *metric.Delta)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", metric.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlStorage) GetCounter(name string) (int64, error) {
+	var value int64
+	err := s.withRetry(func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, `SELECT value FROM counters WHERE name = $1`, name).Scan(&value)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get counter %s: %w", name, err)
+	}
+	return value, nil
+}
+
+func (s *sqlStorage) GetGauge(name string) (float64, error) {
+	var value float64
+	err := s.withRetry(func(ctx context.Context) error {
+		return s.db.QueryRowContext(ctx, `SELECT value FROM gauges WHERE name = $1`, name).Scan(&value)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get gauge %s: %w", name, err)
+	}
+	return value, nil
+}
+
+func (s *sqlStorage) GetMapCounter() (map[string]int64, error) {
+	var counters map[string]int64
+	err := s.withRetry(func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, `SELECT name, value FROM counters`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		counters = make(map[string]int64)
+		for rows.Next() {
+			var name string
+			var value int64
+			if err := rows.Scan(&name, &value); err != nil {
+				return err
+			}
+			counters[name] = value
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counters: %w", err)
+	}
+	return counters, nil
+}
+
+func (s *sqlStorage) GetMapGauge() (map[string]float64, error) {
+	var gauges map[string]float64
+	err := s.withRetry(func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, `SELECT name, value FROM gauges`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		gauges = make(map[string]float64)
+		for rows.Next() {
+			var name string
+			var value float64
+			if err := rows.Scan(&name, &value); err != nil {
+				return err
+			}
+			gauges[name] = value
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gauges: %w", err)
+	}
+	return gauges, nil
+}
+
+// Ping проверяет соединение с базой данных, повторяя попытки при временных ошибках
+func (s *sqlStorage) Ping(ctx context.Context) error {
+	return s.retry.Do(ctx, func() error {
+		return s.db.PingContext(ctx)
+	})
+}
+
+// Close закрывает пул соединений
+func (s *sqlStorage) Close() error {
+	return s.db.Close()
+}
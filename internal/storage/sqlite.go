@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteMigrations — схема хранилища SQLite, новые версии добавляются в конец
+var sqliteMigrations = []migration{
+	{
+		version: 1,
+		query: `CREATE TABLE IF NOT EXISTS counters (
+			name  TEXT PRIMARY KEY,
+			value INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS gauges (
+			name  TEXT PRIMARY KEY,
+			value REAL NOT NULL
+		);`,
+	},
+}
+
+// NewSQLiteStorage открывает (или создаёт) файл базы SQLite и применяет миграции.
+// Каждое изменение фиксируется в базе сразу, отдельные снимки не нужны.
+func NewSQLiteStorage(ctx context.Context, path string, opts ...SQLOption) (*sqlStorage, error) {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite допускает только одного писателя, поэтому все запросы идут через одно соединение
+	db.SetMaxOpenConns(1)
+
+	s := newSQLStorage(db, isSQLiteTransient, opts...)
+	if err := s.Ping(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := s.Migrate(ctx, sqliteMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// isSQLiteTransient отбирает ошибки блокировки базы, которые проходят при повторе
+func isSQLiteTransient(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// младший байт расширенного кода — основной код ошибки
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	for _, tt := range repositoryTests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSQLiteStorage(context.Background(), filepath.Join(t.TempDir(), "metrics.db"))
+			require.NoError(t, err)
+			defer s.Close()
+			tt.fn(t, s)
+		})
+	}
+}
+
+func TestSQLiteStorageReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.db")
+
+	s, err := NewSQLiteStorage(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, s.SetCounter("counter", 10))
+	require.NoError(t, s.SetCounter("counter", 5))
+	require.NoError(t, s.SetGauge("gauge", 1.5))
+	require.NoError(t, s.SetGauge("gauge", 2.5))
+	require.NoError(t, s.Close())
+
+	reopened, err := NewSQLiteStorage(ctx, path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	counter, err := reopened.GetCounter("counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), counter)
+
+	gauge, err := reopened.GetGauge("gauge")
+	require.NoError(t, err)
+	assert.Equal(t, 2.5, gauge)
+
+	_, err = reopened.GetCounter("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = reopened.GetGauge("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
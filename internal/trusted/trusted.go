@@ -0,0 +1,81 @@
+package trusted
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// HeaderName — заголовок, в котором агент передаёт свой IP-адрес
+const HeaderName = "X-Real-IP"
+
+// metadataKey — тот же заголовок в метаданных gRPC
+var metadataKey = strings.ToLower(HeaderName)
+
+// Allowed сообщает, входит ли адрес ip в подсеть subnet. Пустой или неразборчивый адрес не входит.
+func Allowed(subnet *net.IPNet, ip string) bool {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	return parsed != nil && subnet.Contains(parsed)
+}
+
+// Middleware отклоняет с кодом 403 запросы, у которых X-Real-IP отсутствует или лежит вне subnet.
+// Подключается только к обработчикам, изменяющим метрики. При nil subnet обработчик возвращается без изменений.
+func Middleware(subnet *net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if subnet == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Allowed(subnet, r.Header.Get(HeaderName)) {
+				http.Error(w, "address is not in the trusted subnet", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryServerInterceptor проверяет x-real-ip из метаданных для перечисленных методов
+// и отклоняет вызовы вне subnet с кодом PermissionDenied. При nil subnet проверка отключена.
+func UnaryServerInterceptor(subnet *net.IPNet, methods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if subnet == nil || !slices.Contains(methods, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(metadataKey)
+		if len(values) == 0 || !Allowed(subnet, values[0]) {
+			return nil, status.Error(codes.PermissionDenied, "address is not in the trusted subnet")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryClientInterceptor добавляет к каждому вызову IP-адрес агента.
+// При пустом ip метаданные не добавляются.
+func UnaryClientInterceptor(ip string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if ip != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, metadataKey, ip)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// OutboundIP возвращает локальный адрес, с которого уходят пакеты к host.
+// UDP-сокет ничего не отправляет: Dial лишь выбирает маршрут.
+func OutboundIP(host string) (string, error) {
+	conn, err := net.Dial("udp", host)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
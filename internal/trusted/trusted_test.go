@@ -0,0 +1,81 @@
+package trusted
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func mustSubnet(t *testing.T, cidr string) *net.IPNet {
+	t.Helper()
+	_, subnet, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+	return subnet
+}
+
+func TestMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		subnet     string
+		realIP     string
+		wantStatus int
+	}{
+		{name: "inside_subnet", subnet: "192.168.1.0/24", realIP: "192.168.1.10", wantStatus: http.StatusOK},
+		{name: "outside_subnet", subnet: "192.168.1.0/24", realIP: "10.0.0.1", wantStatus: http.StatusForbidden},
+		{name: "missing_header", subnet: "192.168.1.0/24", wantStatus: http.StatusForbidden},
+		{name: "invalid_header", subnet: "192.168.1.0/24", realIP: "not-an-ip", wantStatus: http.StatusForbidden},
+		{name: "ipv6", subnet: "fd00::/8", realIP: "fd00::1", wantStatus: http.StatusOK},
+		{name: "disabled", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subnet *net.IPNet
+			if tt.subnet != "" {
+				subnet = mustSubnet(t, tt.subnet)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/update/", nil)
+			if tt.realIP != "" {
+				r.Header.Set(HeaderName, tt.realIP)
+			}
+			w := httptest.NewRecorder()
+			Middleware(subnet)(ok).ServeHTTP(w, r)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	const method = "/metrics.Metrics/UpdateMetrics"
+	interceptor := UnaryServerInterceptor(mustSubnet(t, "10.0.0.0/8"), method)
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	incoming := func(ip string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(metadataKey, ip))
+	}
+
+	_, err := interceptor(incoming("10.1.2.3"), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	assert.NoError(t, err)
+
+	_, err = interceptor(incoming("192.168.0.1"), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// методы чтения не ограничиваются
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/metrics.Metrics/GetMetric"}, handler)
+	assert.NoError(t, err)
+}